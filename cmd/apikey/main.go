@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	_ "github.com/lib/pq"
+
+	"vio/internal/auth"
+	"vio/internal/database"
+)
+
+var (
+	databaseFlag string
+	nameFlag     string
+	scopesFlag   string
+	quotaFlag    int64
+	idFlag       string
+)
+
+var errUsage = errors.New("usage")
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: %s command [option...]
+
+Commands:
+  issue    issue a new API key (requires -name and -scopes)
+  revoke   revoke the API key (requires -id)
+  list     list all API keys
+
+Options:
+`,
+			os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.StringVar(&databaseFlag, "database", "host=localhost port=25432 dbname=postgres user=postgres password=postgres client_encoding=UTF8 sslmode=disable", "connection string to database")
+	flag.StringVar(&nameFlag, "name", "", "name of the API key owner")
+	flag.StringVar(&scopesFlag, "scopes", string(auth.ScopeLookup), "comma-separated scopes of the API key: lookup, batch, admin")
+	flag.Int64Var(&quotaFlag, "quota", 0, "daily quota of requests, 0 = unlimited")
+	flag.StringVar(&idFlag, "id", "", "ID of the API key")
+	flag.Parse()
+
+	// Options are also accepted after the command.
+	command := flag.Arg(0)
+	if flag.NArg() > 1 {
+		_ = flag.CommandLine.Parse(flag.Args()[1:])
+	}
+
+	if err := run(context.Background(), command); err != nil {
+		if errors.Is(err, errUsage) {
+			flag.Usage()
+			os.Exit(2)
+		}
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, command string) error {
+	switch command {
+	case "issue":
+		if nameFlag == "" || quotaFlag < 0 {
+			return errUsage
+		}
+	case "revoke":
+		if idFlag == "" {
+			return errUsage
+		}
+	case "list":
+	default:
+		return errUsage
+	}
+
+	db, err := database.GetDB(databaseFlag, database.Options{})
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	store := auth.NewStore(db)
+
+	switch command {
+	case "issue":
+		return issue(ctx, store)
+	case "revoke":
+		return revoke(ctx, store)
+	default:
+		return list(ctx, store)
+	}
+}
+
+func issue(ctx context.Context, store *auth.Store) error {
+	scopes, err := auth.ParseScopes(scopesFlag)
+	if err != nil {
+		return err
+	}
+
+	secret, key, err := store.Issue(ctx, nameFlag, scopes, quotaFlag)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "issued API key %d, store it safely: it can not be shown again\n", key.ID)
+	fmt.Println(secret)
+
+	return nil
+}
+
+func revoke(ctx context.Context, store *auth.Store) error {
+	id, err := strconv.ParseInt(idFlag, 10, 64)
+	if err != nil {
+		return errUsage
+	}
+	if err := store.Revoke(ctx, id); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "revoked API key %d\n", id)
+
+	return nil
+}
+
+func list(ctx context.Context, store *auth.Store) error {
+	keys, err := store.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	info, err := json.Marshal(keys)
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(info))
+
+	return nil
+}
@@ -12,14 +12,26 @@
 // Host: localhost:8087
 // Version: 1.0.0
 //
+// Security:
+// - api_key:
+//
+// SecurityDefinitions:
+// api_key:
+//
+//	type: apiKey
+//	name: X-API-Key
+//	in: header
+//
 // swagger:meta
 package main
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -29,11 +41,21 @@ import (
 	_ "github.com/lib/pq"
 
 	"vio/internal/api"
+	"vio/internal/auth"
 	"vio/internal/config"
 	"vio/internal/database"
+	"vio/internal/grpcapi"
+	"vio/internal/imports"
+	"vio/internal/lib/clientip"
 	"vio/internal/lib/logger/sl"
+	"vio/internal/lib/ratelimit"
+	"vio/internal/lib/tlsconfig"
+	"vio/internal/processes"
+	"vio/internal/shape"
 
 	"github.com/gorilla/mux"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
 func main() {
@@ -55,7 +77,12 @@ func run(log *slog.Logger, cfg *config.Config) error {
 	log.Debug("starting geolocation server")
 
 	log.Debug("starting db connect ", "connect", cfg.DBConnect)
-	db, err := database.GetDB(cfg.DBConnect)
+	db, err := database.GetDB(cfg.DBConnect, database.Options{
+		MaxOpenConns:     cfg.Database.MaxOpenConns,
+		MaxIdleConns:     cfg.Database.MaxIdleConns,
+		ConnMaxLifetime:  cfg.Database.ConnMaxLifetime,
+		StatementTimeout: cfg.Database.StatementTimeout,
+	})
 	if err != nil {
 		return err
 	}
@@ -63,9 +90,104 @@ func run(log *slog.Logger, cfg *config.Config) error {
 
 	log.Debug("db connected successfully")
 
+	bgCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+
+	spatialIndex := processes.NewSpatialIndex()
+	go refreshSpatialIndex(bgCtx, log, db, spatialIndex, cfg.SpatialIndexRefresh)
+
+	resolver, err := clientip.NewResolver(cfg.TrustedProxies)
+	if err != nil {
+		return err
+	}
+
+	shaper, err := shape.New(cfg.ResponseFields)
+	if err != nil {
+		return err
+	}
+
+	bogons, err := processes.ParseBogonPolicy(cfg.Bogons)
+	if err != nil {
+		return err
+	}
+
+	countries, err := processes.ParseCountryPolicy(cfg.Imports.Countries)
+	if err != nil {
+		return err
+	}
+	coordinates, err := processes.ParseCoordinatePolicy(cfg.Imports.Coordinates)
+	if err != nil {
+		return err
+	}
+	if cfg.Imports.MinQuality < 0 || cfg.Imports.MinQuality > processes.MaxQualityScore {
+		return fmt.Errorf("invalid imports min_quality %d: must be between 0 and %d", cfg.Imports.MinQuality, processes.MaxQualityScore)
+	}
+
 	router := mux.NewRouter()
 
-	router.HandleFunc("/api/geolocation/{ip_address}", api.GetGeoLocation(db)).Methods("GET")
+	// Batch routes are registered first, so /api/geolocation/nearby is not taken for an IP address.
+	batch := router.NewRoute().Subrouter()
+	batch.HandleFunc("/api/geolocation/nearby", api.GetNearbyLocations(spatialIndex, shaper)).Methods("GET")
+	batch.HandleFunc("/api/locations", api.GetLocations(db, shaper)).Methods("GET")
+	batch.HandleFunc("/api/stats/countries", api.GetCountryStatistics(db, shaper)).Methods("GET")
+	batch.HandleFunc("/api/stats/cities", api.GetCityStatistics(db, shaper)).Methods("GET")
+
+	lookup := router.NewRoute().Subrouter()
+	lookup.HandleFunc("/api/geolocation/me", api.GetMyGeoLocation(db, resolver, shaper, bogons)).Methods("GET")
+	lookup.HandleFunc("/api/geolocation/{ip_address}", api.GetGeoLocation(db, shaper, bogons)).Methods("GET")
+
+	var (
+		rateLimiter     *api.RateLimiter
+		grpcRateLimiter *grpcapi.RateLimiter
+	)
+	if cfg.RateLimit.Enabled {
+		// The gRPC API takes tokens from the same buckets, so clients can not double their rate.
+		byIP := ratelimit.New(cfg.RateLimit.IPRate, cfg.RateLimit.IPBurst)
+		byKey := ratelimit.New(cfg.RateLimit.KeyRate, cfg.RateLimit.KeyBurst)
+		rateLimiter = api.NewRateLimiter(resolver, byIP, byKey)
+		grpcRateLimiter = grpcapi.NewRateLimiter(byIP, byKey)
+		// Limit by IP runs before authentication, so guessing keys does not load the database.
+		router.Use(rateLimiter.LimitByIP)
+	}
+
+	importManager := imports.NewManager(bgCtx, db, log, imports.Options{
+		SourceDir:    cfg.Imports.SourceDir,
+		UploadDir:    cfg.Imports.UploadDir,
+		KeepFinished: cfg.Imports.KeepFinished,
+		Import: processes.ImportOptions{
+			Parallel:    cfg.Imports.Parallel,
+			BatchSize:   cfg.Imports.BatchSize,
+			PoolReserve: cfg.Imports.PoolReserve,
+			Validation: processes.ValidationPolicy{
+				Bogons:      bogons,
+				Countries:   countries,
+				Coordinates: coordinates,
+				MinQuality:  cfg.Imports.MinQuality,
+			},
+		},
+	})
+
+	var keyStore *auth.Store
+	if cfg.AuthEnabled {
+		keyStore = auth.NewStore(db)
+		authenticator := api.NewAuthenticator(keyStore, rateLimiter, log)
+		lookup.Use(authenticator.Require(auth.ScopeLookup))
+		batch.Use(authenticator.Require(auth.ScopeBatch))
+
+		// Admin routes are available only with authentication.
+		admin := router.NewRoute().Subrouter()
+		admin.HandleFunc("/api/admin/imports", api.CreateImport(importManager, cfg.Imports.MaxUploadSize)).Methods("POST")
+		admin.HandleFunc("/api/admin/imports", api.GetImports(importManager)).Methods("GET")
+		admin.HandleFunc("/api/admin/imports/{id}", api.GetImport(importManager)).Methods("GET")
+		admin.HandleFunc("/api/admin/imports/{id}", api.CancelImport(importManager)).Methods("DELETE")
+		admin.Use(authenticator.Require(auth.ScopeAdmin))
+	} else {
+		log.Warn("API key authentication is disabled, admin API is not available")
+	}
+
+	// The timeout applies to the queries of lookups and batch requests, not to uploads of imports.
+	lookup.Use(api.QueryTimeout(cfg.Database.QueryTimeout))
+	batch.Use(api.QueryTimeout(cfg.Database.QueryTimeout))
 
 	done := make(chan os.Signal, 1)
 	signal.Notify(done, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
@@ -77,15 +199,71 @@ func run(log *slog.Logger, cfg *config.Config) error {
 		Handler: router,
 	}
 
+	if cfg.TLS.Enabled {
+		reloader, err := tlsconfig.NewReloader(tlsconfig.Options{
+			CertFile:     cfg.TLS.CertFile,
+			KeyFile:      cfg.TLS.KeyFile,
+			ClientCAFile: cfg.TLS.ClientCAFile,
+			ClientAuth:   cfg.TLS.ClientAuth,
+			MinVersion:   cfg.TLS.MinVersion,
+			CipherSuites: cfg.TLS.CipherSuites,
+		}, log)
+		if err != nil {
+			return err
+		}
+		srv.TLSConfig = reloader.Config()
+
+		go func() {
+			if err := reloader.Watch(bgCtx); err != nil {
+				log.Error("failed to watch TLS files", sl.Err(err))
+			}
+		}()
+	}
+
 	go func() {
-		if err := srv.ListenAndServe(); err != nil {
+		var err error
+		if srv.TLSConfig != nil {
+			// The certificate is provided by the TLS config.
+			err = srv.ListenAndServeTLS("", "")
+		} else {
+			err = srv.ListenAndServe()
+		}
+		if err != nil {
 			if !errors.Is(err, http.ErrServerClosed) {
 				log.Error("failed to start server", "error", err)
 			}
 		}
 	}()
 
-	log.Info("started geolocation server", "port", cfgAddress)
+	log.Info("started geolocation server", "port", cfgAddress, "tls", cfg.TLS.Enabled)
+
+	var grpcServer *grpc.Server
+	if cfg.GRPC.Enabled {
+		var serverOpts []grpc.ServerOption
+		if srv.TLSConfig != nil {
+			serverOpts = append(serverOpts, grpc.Creds(credentials.NewTLS(srv.TLSConfig)))
+		}
+		grpcServer = grpcapi.NewServer(db, keyStore, log, grpcapi.Options{
+			Shaper:       shaper,
+			RateLimiter:  grpcRateLimiter,
+			QueryTimeout: cfg.Database.QueryTimeout,
+			Bogons:       bogons,
+		}, serverOpts...)
+
+		grpcAddress := fmt.Sprintf(":%d", cfg.GRPC.Port)
+		listener, err := net.Listen("tcp", grpcAddress)
+		if err != nil {
+			return err
+		}
+
+		go func() {
+			if err := grpcServer.Serve(listener); err != nil {
+				log.Error("failed to start gRPC server", "error", err)
+			}
+		}()
+
+		log.Info("started gRPC server", "port", grpcAddress, "tls", cfg.TLS.Enabled)
+	}
 
 	<-done
 	log.Info("stopping geolocation server")
@@ -93,13 +271,61 @@ func run(log *slog.Logger, cfg *config.Config) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	if grpcServer != nil {
+		stopGRPC(ctx, grpcServer)
+	}
+
 	if err := srv.Shutdown(ctx); err != nil {
 		log.Error("failed to stop server", "error", err)
 
 		return err
 	}
 
+	// Running imports are cancelled, locations written before are kept.
+	stopBackground()
+	importManager.Wait()
+
 	log.Info("geolocation server stopped")
 
 	return nil
 }
+
+// stopGRPC waits for running calls to finish, the remaining calls are cancelled when the context is done.
+func stopGRPC(ctx context.Context, srv *grpc.Server) {
+	stopped := make(chan struct{})
+	go func() {
+		srv.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		srv.Stop()
+	}
+}
+
+// refreshSpatialIndex loads locations into the spatial index and reloads them periodically.
+func refreshSpatialIndex(ctx context.Context, log *slog.Logger, db *sql.DB, index *processes.SpatialIndex, interval time.Duration) {
+	for {
+		startTime := time.Now()
+		if err := index.Load(ctx, db); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Error("failed to load spatial index", sl.Err(err))
+		} else {
+			log.Info("spatial index loaded", slog.String("load_time", time.Since(startTime).String()))
+		}
+
+		if interval <= 0 {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
@@ -1,21 +1,49 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"flag"
 	"fmt"
+	"log/slog"
 	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
 
 	_ "github.com/lib/pq"
 
+	"vio/internal/config"
+	"vio/internal/daemon"
+	"vio/internal/database"
 	"vio/internal/processes"
+	"vio/internal/report"
 )
 
 var (
-	sourceFlag   string
-	databaseFlag string
-	parallelFlag string
-	helpFlag     string
+	configFlag      string
+	profileFlag     string
+	sourceFlag      string
+	databaseFlag    string
+	formatFlag      string
+	duplicatesFlag  string
+	parallelFlag    string
+	batchSizeFlag   int
+	bogonsFlag      string
+	countriesFlag   string
+	coordinatesFlag string
+	minQualityFlag  string
+	helpFlag        string
+	watchFlag       bool
+	scheduleFlag    string
+	settleFlag      time.Duration
+	processedFlag   string
+	failedFlag      string
+	progressFlag    time.Duration
+
+	// columns maps the location fields to the header columns, it is set by the config only.
+	columns map[string]string
 )
 
 var errUsage = errors.New("usage")
@@ -29,29 +57,230 @@ Options:
 			os.Args[0])
 		flag.PrintDefaults()
 	}
-	flag.StringVar(&helpFlag, "help", "", "show help about arguments")
-	flag.StringVar(&sourceFlag, "source", "data_source", "directory of input data files")
-	flag.StringVar(&databaseFlag, "database", "host=localhost port=25432 dbname=postgres user=postgres password=postgres client_encoding=UTF8 sslmode=disable", "connection string to database")
-	flag.StringVar(&parallelFlag, "parallel", "0", "parallel processing -1 = off, N = count of goroutines, 0 = count of CPU cores")
+	defineFlags()
 	flag.Parse()
 	if len(helpFlag) > 0 {
 		flag.Usage()
 		os.Exit(2)
 	}
 
-	info, err := run()
+	// The import is stopped gracefully on SIGINT or SIGTERM, a second signal kills the process.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	info, err := run(ctx)
+	// Partial statistics are printed when the import is aborted.
+	if info != nil {
+		fmt.Println(string(info))
+	}
 	if err != nil {
 		if errors.Is(err, errUsage) {
 			flag.Usage()
 			os.Exit(2)
 		}
+		if errors.Is(err, context.Canceled) {
+			fmt.Fprintf(os.Stderr, "import aborted\n")
+			os.Exit(130)
+		}
 		fmt.Fprintf(os.Stderr, "error: %s\n", err)
 		os.Exit(1)
 	}
+}
+
+// defineFlags defines the command line flags.
+func defineFlags() {
+	flag.StringVar(&helpFlag, "help", "", "show help about arguments")
+	flag.StringVar(&configFlag, "config", os.Getenv("LOADER_CONFIG_PATH"), "YAML config of imports (default $LOADER_CONFIG_PATH), flags given on the command line override its values")
+	flag.StringVar(&profileFlag, "profile", "", "profile of the config, e.g. a vendor feed (default profile of the config)")
+	flag.StringVar(&sourceFlag, "source", "data_source", "directory of input data files")
+	flag.StringVar(&databaseFlag, "database", "host=localhost port=25432 dbname=postgres user=postgres password=postgres client_encoding=UTF8 sslmode=disable", "connection string to database")
+	flag.StringVar(&formatFlag, "format", "csv", "format of input data files: csv, tsv")
+	flag.StringVar(&duplicatesFlag, "duplicates", "last", "records of a repeated IP address: last = newer replaces previous, first = later are discarded and stored are kept")
+	flag.StringVar(&parallelFlag, "parallel", "0", "parallel processing -1 = off, N = count of goroutines, 0 = count of CPU cores, auto = adaptive by throughput")
+	flag.IntVar(&batchSizeFlag, "batch-size", 1, "number of locations written with one statement, 1 = one by one")
+	flag.StringVar(&bogonsFlag, "bogons", "accept", "special-purpose addresses (private, loopback, documentation, etc.): accept, reject = discard, tag = count in statistics")
+	flag.StringVar(&countriesFlag, "countries", "off", "records whose country code and country name disagree: off, flag = count in statistics, correct, reject = discard")
+	flag.StringVar(&coordinatesFlag, "coordinates", "off", "records whose coordinates are outside of the country: off, flag = count in statistics, reject = discard")
+	flag.StringVar(&minQualityFlag, "min-quality", "0", "discard locations with a lower quality score (0-100), 0 = import all")
+	flag.BoolVar(&watchFlag, "watch", false, "run as daemon importing new files of the source directory as they appear")
+	flag.StringVar(&scheduleFlag, "schedule", "", "run as daemon polling the source directory on a cron expression, e.g. \"*/5 * * * *\"")
+	flag.DurationVar(&settleFlag, "settle", 10*time.Second, "daemon: time a file must stay unchanged before it is imported")
+	flag.StringVar(&processedFlag, "processed", "", "daemon: directory of imported files (default <source>/processed)")
+	flag.DurationVar(&progressFlag, "progress", 10*time.Second, "interval of progress log lines when the output is not a terminal, 0 = off")
+	flag.StringVar(&failedFlag, "failed", "", "daemon: directory of files failed to import (default <source>/failed)")
+}
+
+func run(ctx context.Context) ([]byte, error) {
+	if err := applyConfig(); err != nil {
+		return nil, err
+	}
+	if watchFlag || scheduleFlag != "" {
+		return nil, runDaemon(ctx)
+	}
+
+	opts, err := importOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	// The progress bar is drawn on stderr, so the statistics on stdout can be piped.
+	progress := &processes.Progress{}
+	reporter := report.New(os.Stderr, newLogger(), progress.Snapshot, progressFlag)
+	reportCtx, stopReport := context.WithCancel(ctx)
+	reported := make(chan struct{})
+	go func() {
+		reporter.Run(reportCtx)
+		close(reported)
+	}()
 
-	fmt.Println(string(info))
+	info, err := processes.RunOnce(ctx, sourceFlag, databaseFlag, opts, progress)
+	stopReport()
+	<-reported
+
+	return info, err
 }
 
-func run() ([]byte, error) {
-	return processes.RunOnce(sourceFlag, databaseFlag, parallelFlag)
+// applyConfig sets the flags not given on the command line to the values of the config profile.
+func applyConfig() error {
+	cfg, err := config.LoadLoader(configFlag)
+	if err != nil {
+		return err
+	}
+	profile, err := cfg.ImportProfile(profileFlag)
+	if err != nil {
+		return err
+	}
+
+	given := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		given[f.Name] = true
+	})
+	values := map[string]string{
+		"source":      profile.Source,
+		"database":    cfg.DBConnect,
+		"format":      profile.Format,
+		"duplicates":  profile.Duplicates,
+		"parallel":    profile.Parallel,
+		"batch-size":  strconv.Itoa(profile.BatchSize),
+		"bogons":      profile.Bogons,
+		"countries":   profile.Countries,
+		"coordinates": profile.Coordinates,
+		"min-quality": profile.MinQuality,
+	}
+	for name, value := range values {
+		if given[name] {
+			continue
+		}
+		if err := flag.Set(name, value); err != nil {
+			return fmt.Errorf("invalid %s of the config: %w", name, err)
+		}
+	}
+	columns = profile.Columns
+
+	return nil
+}
+
+// importOptions returns the options of imports set by the flags and the config.
+func importOptions() (processes.ImportOptions, error) {
+	parallel, err := processes.ParseParallel(parallelFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		return processes.ImportOptions{}, errUsage
+	}
+	format, err := processes.ParseSourceFormat(formatFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		return processes.ImportOptions{}, errUsage
+	}
+	columnMapping, err := processes.ParseColumnMapping(columns)
+	if err != nil {
+		return processes.ImportOptions{}, err
+	}
+	duplicates, err := processes.ParseDuplicatePolicy(duplicatesFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		return processes.ImportOptions{}, errUsage
+	}
+	bogons, err := processes.ParseBogonPolicy(bogonsFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		return processes.ImportOptions{}, errUsage
+	}
+	countries, err := processes.ParseCountryPolicy(countriesFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		return processes.ImportOptions{}, errUsage
+	}
+	coordinates, err := processes.ParseCoordinatePolicy(coordinatesFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		return processes.ImportOptions{}, errUsage
+	}
+	minQuality, err := processes.ParseMinQuality(minQualityFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		return processes.ImportOptions{}, errUsage
+	}
+
+	return processes.ImportOptions{
+		Parallel:   parallel,
+		BatchSize:  batchSizeFlag,
+		Format:     format,
+		Columns:    columnMapping,
+		Duplicates: duplicates,
+		Validation: processes.ValidationPolicy{
+			Bogons:      bogons,
+			Countries:   countries,
+			Coordinates: coordinates,
+			MinQuality:  minQuality,
+		},
+	}, nil
+}
+
+func newLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(os.Stderr, nil))
+}
+
+// runDaemon imports new files of the source directory until the context is done.
+func runDaemon(ctx context.Context) error {
+	if watchFlag && scheduleFlag != "" {
+		fmt.Fprintf(os.Stderr, "-watch and -schedule are mutually exclusive\n")
+		return errUsage
+	}
+	opts, err := importOptions()
+	if err != nil {
+		return err
+	}
+
+	log := newLogger()
+
+	// The database is used only by the imports, so the pool follows the workers.
+	db, err := database.GetDB(databaseFlag, processes.PoolOptions(opts.Parallel))
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	d, err := daemon.New(db, log, daemon.Options{
+		Source:       sourceFlag,
+		ProcessedDir: processedFlag,
+		FailedDir:    failedFlag,
+		Schedule:     scheduleFlag,
+		Settle:       settleFlag,
+		Import:       opts,
+		Progress:     progressFlag,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := d.Run(ctx); err != nil {
+		return err
+	}
+	log.Info("loader daemon stopped")
+
+	return nil
 }
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_applyConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "loader.yaml")
+	content := `
+import:
+  countries: "flag"
+  min_quality: 10
+profiles:
+  acme:
+    parallel: "auto"
+    batch_size: 1000
+    min_quality: 50
+`
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	t.Setenv("LOADER_PARALLEL", "8")
+	t.Setenv("LOADER_BATCH_SIZE", "500")
+
+	commandLine := flag.CommandLine
+	t.Cleanup(func() { flag.CommandLine = commandLine })
+	flag.CommandLine = flag.NewFlagSet("loader", flag.ContinueOnError)
+	defineFlags()
+	require.NoError(t, flag.CommandLine.Parse([]string{"-config=" + path, "-profile=acme", "-batch-size=7"}))
+
+	require.NoError(t, applyConfig())
+
+	// Flags override the environment, the environment the profile and the profile the import section.
+	assert.Equal(t, 7, batchSizeFlag)
+	assert.Equal(t, "8", parallelFlag)
+	assert.Equal(t, "50", minQualityFlag)
+	assert.Equal(t, "flag", countriesFlag)
+	assert.Equal(t, "csv", formatFlag)
+}
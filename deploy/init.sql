@@ -14,7 +14,7 @@ SET client_min_messages = warning;
 SET row_security = off;
 
 CREATE TABLE IF NOT EXISTS location (
-    ip_address VARCHAR(15) not null,
+    ip_address VARCHAR(45) not null,
     country_code VARCHAR(2),
     country VARCHAR(250),
     city VARCHAR(250),
@@ -35,6 +35,54 @@ COMMENT ON COLUMN location.latitude IS 'Latitude of the location';
 COMMENT ON COLUMN location.longitude IS 'Longitude of the location';
 COMMENT ON COLUMN location.mystery_value IS 'A mysterious value associated with the location';
 
+-- Databases created with VARCHAR(15) can not store IPv6 addresses.
+ALTER TABLE location ALTER COLUMN ip_address TYPE VARCHAR(45);
+
+ALTER TABLE location ADD COLUMN IF NOT EXISTS country_original TEXT;
+ALTER TABLE location ADD COLUMN IF NOT EXISTS city_original TEXT;
+
+COMMENT ON COLUMN location.country_original IS 'Country of the source file changed by the normalization or the correction, NULL when unchanged';
+COMMENT ON COLUMN location.city_original IS 'City of the source file changed by the normalization, NULL when unchanged';
+
+ALTER TABLE location ADD COLUMN IF NOT EXISTS quality_score SMALLINT not null default 0;
+ALTER TABLE location ADD COLUMN IF NOT EXISTS quality_flags TEXT[] not null default '{}';
+
+COMMENT ON COLUMN location.quality_score IS 'Data quality of the location from 0 to 100, 0 for locations imported before scoring';
+COMMENT ON COLUMN location.quality_flags IS 'Data quality issues of the location, e.g. missing_city, zero_coordinates';
+
+CREATE INDEX IF NOT EXISTS location_quality_score_idx ON location (quality_score, ip_address);
+
+CREATE INDEX IF NOT EXISTS location_country_code_idx ON location (country_code, ip_address);
+CREATE INDEX IF NOT EXISTS location_country_idx ON location (country, ip_address);
+CREATE INDEX IF NOT EXISTS location_city_idx ON location (city, ip_address);
+CREATE INDEX IF NOT EXISTS location_coordinates_idx ON location (latitude, longitude);
+
+CREATE TABLE IF NOT EXISTS api_key (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(250) not null,
+    key_prefix VARCHAR(16) not null,
+    key_hash CHAR(64) not null UNIQUE,
+    scopes TEXT[] not null,
+    daily_quota BIGINT not null default 0,
+    created_at TIMESTAMPTZ not null default now(),
+    revoked_at TIMESTAMPTZ
+    );
+
+COMMENT ON TABLE api_key IS 'API keys of the geolocation service';
+COMMENT ON COLUMN api_key.key_prefix IS 'First characters of the key to identify it';
+COMMENT ON COLUMN api_key.key_hash IS 'SHA-256 hash of the key';
+COMMENT ON COLUMN api_key.scopes IS 'Scopes granted to the key: lookup, batch, admin';
+COMMENT ON COLUMN api_key.daily_quota IS 'Maximum number of requests per day (UTC), 0 means unlimited';
+
+CREATE TABLE IF NOT EXISTS api_key_usage (
+    api_key_id BIGINT not null REFERENCES api_key (id) ON DELETE CASCADE,
+    day DATE not null,
+    requests BIGINT not null default 0,
+    PRIMARY KEY (api_key_id, day)
+    );
+
+COMMENT ON TABLE api_key_usage IS 'Daily usage counters of API keys';
+
 INSERT INTO location (ip_address, country_code, country, city, latitude, longitude, mystery_value) VALUES ('70.95.73.73', 'TL', 'Saudi Arabia', 'Gradymouth', -49.16675918861615, -86.05920084416894, 2559997162)
     ON CONFLICT (ip_address) DO NOTHING
 ;
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"vio/internal/auth"
+	"vio/internal/lib/logger/sl"
+
+	"github.com/gorilla/mux"
+)
+
+// Authenticator checks API keys of requests, limits their rate and counts their daily usage.
+type Authenticator struct {
+	store   *auth.Store
+	limiter *RateLimiter
+	log     *slog.Logger
+}
+
+// NewAuthenticator creates the authenticator, a nil limiter disables the rate limit per API key.
+func NewAuthenticator(store *auth.Store, limiter *RateLimiter, log *slog.Logger) *Authenticator {
+	return &Authenticator{
+		store:   store,
+		limiter: limiter,
+		log:     log,
+	}
+}
+
+// Require returns a middleware that accepts only requests with an active API key granted the scope.
+// The key is read from the X-API-Key header or the Authorization header with the Bearer scheme.
+func (a *Authenticator) Require(scope auth.Scope) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			secret := apiKeyFromRequest(r)
+			if secret == "" {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				http.Error(w, "API key is required", http.StatusUnauthorized)
+				return
+			}
+
+			key, err := a.store.Authenticate(r.Context(), secret)
+			if err != nil {
+				if errors.Is(err, auth.ErrInvalidKey) {
+					w.Header().Set("WWW-Authenticate", "Bearer")
+					http.Error(w, "Invalid API key", http.StatusUnauthorized)
+					return
+				}
+				a.log.Error("failed to authenticate API key", sl.Err(err))
+				http.Error(w, "Failed to authenticate", http.StatusInternalServerError)
+				return
+			}
+
+			if !key.HasScope(scope) {
+				http.Error(w, "API key is not allowed to access this resource", http.StatusForbidden)
+				return
+			}
+			// Requests rejected by the rate limit do not consume the daily quota.
+			if a.limiter != nil && !a.limiter.allowKey(w, key) {
+				return
+			}
+
+			requests, err := a.store.ConsumeQuota(r.Context(), key.ID)
+			if err != nil {
+				a.log.Error("failed to count API key usage", sl.Err(err), slog.Int64("key_id", key.ID))
+				http.Error(w, "Failed to authenticate", http.StatusInternalServerError)
+				return
+			}
+			if key.DailyQuota > 0 {
+				remaining := key.DailyQuota - requests
+				if remaining < 0 {
+					remaining = 0
+				}
+				w.Header().Set("X-Quota-Limit", strconv.FormatInt(key.DailyQuota, 10))
+				w.Header().Set("X-Quota-Remaining", strconv.FormatInt(remaining, 10))
+				if requests > key.DailyQuota {
+					http.Error(w, "Daily quota exceeded", http.StatusTooManyRequests)
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r.WithContext(auth.WithAPIKey(r.Context(), key)))
+		})
+	}
+}
+
+func apiKeyFromRequest(r *http.Request) string {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return strings.TrimSpace(key)
+	}
+
+	scheme, token, found := strings.Cut(r.Header.Get("Authorization"), " ")
+	if found && strings.EqualFold(scheme, "Bearer") {
+		return strings.TrimSpace(token)
+	}
+
+	return ""
+}
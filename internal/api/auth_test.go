@@ -0,0 +1,132 @@
+package api
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"vio/internal/auth"
+	"vio/internal/lib/clientip"
+	"vio/internal/lib/ratelimit"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthenticator_Require(t *testing.T) {
+	const secret = "vio_test-secret"
+
+	tests := []struct {
+		name       string
+		header     string
+		value      string
+		scopes     string
+		dailyQuota int64
+		requests   int64
+		// rateLimited exhausts the rate limit of the key before the request.
+		rateLimited bool
+		wantStatus  int
+	}{
+		{
+			name:       "Missing key",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "Valid key in X-API-Key",
+			header:     "X-API-Key",
+			value:      secret,
+			scopes:     "{lookup}",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Valid key in Authorization",
+			header:     "Authorization",
+			value:      "Bearer " + secret,
+			scopes:     "{lookup}",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Scope is not granted",
+			header:     "X-API-Key",
+			value:      secret,
+			scopes:     "{batch}",
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "Daily quota exceeded",
+			header:     "X-API-Key",
+			value:      secret,
+			scopes:     "{lookup}",
+			dailyQuota: 10,
+			requests:   11,
+			wantStatus: http.StatusTooManyRequests,
+		},
+		{
+			name:        "Rate limit exceeded does not consume the quota",
+			header:      "X-API-Key",
+			value:       secret,
+			scopes:      "{lookup}",
+			dailyQuota:  10,
+			rateLimited: true,
+			wantStatus:  http.StatusTooManyRequests,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer db.Close()
+
+			if tt.scopes != "" {
+				columns := []string{"id", "name", "key_prefix", "scopes", "daily_quota", "created_at", "revoked_at"}
+				mock.ExpectQuery(regexp.QuoteMeta(auth.SQLSelectAPIKeyByHash)).
+					WillReturnRows(sqlmock.NewRows(columns).AddRow(1, "test", "vio_test", tt.scopes, tt.dailyQuota, time.Now(), nil))
+			}
+			if (tt.wantStatus == http.StatusOK || tt.wantStatus == http.StatusTooManyRequests) && !tt.rateLimited {
+				mock.ExpectQuery(regexp.QuoteMeta(auth.SQLConsumeQuota)).
+					WithArgs(1).
+					WillReturnRows(sqlmock.NewRows([]string{"requests"}).AddRow(tt.requests))
+			}
+
+			resolver, err := clientip.NewResolver(nil)
+			require.NoError(t, err)
+			byKey := ratelimit.New(0, 1)
+			if tt.rateLimited {
+				byKey.Allow("key:1")
+			}
+			limiter := NewRateLimiter(resolver, ratelimit.New(0, 1), byKey)
+
+			authenticator := NewAuthenticator(auth.NewStore(db), limiter, slog.New(slog.NewTextHandler(io.Discard, nil)))
+			handler := authenticator.Require(auth.ScopeLookup)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.NotNil(t, auth.FromContext(r.Context()))
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/api/geolocation/70.95.73.73", nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			if tt.rateLimited {
+				assert.NotEmpty(t, rec.Header().Get("Retry-After"))
+				assert.Empty(t, rec.Header().Get("X-Quota-Remaining"))
+			}
+			if err = mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
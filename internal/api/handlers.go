@@ -1,17 +1,23 @@
 package api
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"net/http"
 
+	"vio/internal/lib/clientip"
+	"vio/internal/models"
 	"vio/internal/processes"
+	"vio/internal/shape"
 
 	"github.com/gorilla/mux"
 )
 
 // swagger:operation  GET /api/geolocation/{ip_address} GetGeoLocation
 // Get Geo Location by IP address.
+// Special-purpose addresses (private, loopback, documentation, etc.) are answered as reserved
+// addresses depending on the bogon policy of the service.
 // ---
 // produces:
 // - application/json
@@ -21,6 +27,14 @@ import (
 //     description: IP address
 //     required: true
 //     type: string
+//   - in: query
+//     name: fields
+//     description: Comma-separated list of fields to return
+//     type: string
+//   - in: query
+//     name: lang
+//     description: Language of the country name (BCP 47 tag)
+//     type: string
 //
 // responses:
 //
@@ -28,11 +42,19 @@ import (
 //	  description: OK
 //	  schema:
 //	    $ref: '#/definitions/Location'
+//	'400':
+//	  description: Invalid IP address or query parameters
 //	'404':
 //	  description: Error
+//	'422':
+//	  description: Reserved special-purpose address
+//	  schema:
+//	    $ref: '#/definitions/ReservedAddress'
 //	'500':
 //	  description: Internal Server error
-func GetGeoLocation(db *sql.DB) http.HandlerFunc {
+//	'504':
+//	  description: Query timeout exceeded
+func GetGeoLocation(db *sql.DB, shaper *shape.Shaper, bogons processes.BogonPolicy) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		vars := mux.Vars(r)
 		ipAddress := vars["ip_address"]
@@ -41,21 +63,120 @@ func GetGeoLocation(db *sql.DB) http.HandlerFunc {
 			ipAddress = processes.ExtractIPAddress(r.URL.String())
 		}
 
-		if !processes.IsValidIPAddress(ipAddress) {
-			http.Error(w, "Invalid IP address", http.StatusBadRequest)
+		sh, err := shaper.Parse(r.URL.Query(), models.Location{})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		writeLocation(r.Context(), w, db, sh, bogons, ipAddress)
+	}
+}
+
+// swagger:operation  GET /api/geolocation/me GetMyGeoLocation
+// Get Geo Location of the client IP address.
+// The address is taken from the connection or from the proxy headers
+// (Forwarded, X-Forwarded-For, X-Real-IP) set by a trusted proxy.
+// ---
+// produces:
+// - application/json
+// parameters:
+//   - in: query
+//     name: fields
+//     description: Comma-separated list of fields to return
+//     type: string
+//   - in: query
+//     name: lang
+//     description: Language of the country name (BCP 47 tag)
+//     type: string
+//
+// responses:
+//
+//	'200':
+//	  description: OK
+//	  schema:
+//	    $ref: '#/definitions/Location'
+//	'400':
+//	  description: Client IP address can not be determined or invalid query parameters
+//	'404':
+//	  description: Error
+//	'422':
+//	  description: Reserved special-purpose address
+//	  schema:
+//	    $ref: '#/definitions/ReservedAddress'
+//	'500':
+//	  description: Internal Server error
+//	'504':
+//	  description: Query timeout exceeded
+func GetMyGeoLocation(db *sql.DB, resolver *clientip.Resolver, shaper *shape.Shaper, bogons processes.BogonPolicy) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		addr, err := resolver.ClientIP(r)
+		if err != nil {
+			http.Error(w, "Client IP address can not be determined", http.StatusBadRequest)
 			return
 		}
-		location, err := processes.GetLocation(db, ipAddress)
+		sh, err := shaper.Parse(r.URL.Query(), models.Location{})
 		if err != nil {
-			http.Error(w, "Failed to retrieve location", http.StatusInternalServerError)
+			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
-		if len(location.IPAddress) == 0 {
-			http.Error(w, "Location not found", http.StatusNotFound)
+
+		writeLocation(r.Context(), w, db, sh, bogons, addr.String())
+	}
+}
+
+func writeLocation(ctx context.Context, w http.ResponseWriter, db *sql.DB, sh *shape.Shape, bogons processes.BogonPolicy, ipAddress string) {
+	addr, ok := processes.ParseIPAddress(ipAddress)
+	if !ok {
+		http.Error(w, "Invalid IP address", http.StatusBadRequest)
+		return
+	}
+	// Locations are stored by the canonical form of the address.
+	ipAddress = addr.String()
+	class, reserved := bogons.Reserved(addr)
+	if reserved && bogons == processes.BogonsReject {
+		// Rejected addresses are never imported.
+		writeReservedAddress(w, ipAddress, class)
+		return
+	}
+	location, err := processes.GetLocation(ctx, db, ipAddress)
+	if err != nil {
+		writeQueryError(ctx, w, err, "Failed to retrieve location")
+		return
+	}
+	if len(location.IPAddress) == 0 {
+		if reserved {
+			writeReservedAddress(w, ipAddress, class)
 			return
 		}
+		http.Error(w, "Location not found", http.StatusNotFound)
+		return
+	}
+	if reserved {
+		location.AddressClass = string(class)
+	}
 
-		w.Header().Set("Content-Type", "application/json")
-		_ = json.NewEncoder(w).Encode(location)
+	data, err := sh.Apply(location)
+	if err != nil {
+		http.Error(w, "Failed to encode location", http.StatusInternalServerError)
+		return
 	}
+
+	writeJSON(w, data)
+}
+
+// writeReservedAddress answers a special-purpose address which has no public location.
+func writeReservedAddress(w http.ResponseWriter, ipAddress string, class processes.AddressClass) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	_ = json.NewEncoder(w).Encode(models.ReservedAddress{
+		IPAddress:    ipAddress,
+		AddressClass: string(class),
+		Message:      "Reserved address",
+	})
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
 }
@@ -10,11 +10,16 @@ import (
 
 	_ "github.com/lib/pq"
 
+	"vio/internal/lib/clientip"
 	"vio/internal/models"
+	"vio/internal/processes"
+	"vio/internal/shape"
 	"vio/internal/testhelpers"
 
+	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/google/go-cmp/cmp"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestGetGeoLocation(t *testing.T) {
@@ -34,7 +39,10 @@ func TestGetGeoLocation(t *testing.T) {
 	}
 
 	// Creating a test HTTP server.
-	server := httptest.NewServer(GetGeoLocation(testDB.DB()))
+	shaper, err := shape.New(nil)
+	assert.NoError(t, err)
+
+	server := httptest.NewServer(GetGeoLocation(testDB.DB(), shaper, processes.BogonsAccept))
 	defer server.Close()
 
 	// Use the server.URL and append the IP address to it
@@ -70,3 +78,109 @@ func TestGetGeoLocation(t *testing.T) {
 		t.Fatal("result mismatch\n", diff)
 	}
 }
+
+func TestGetMyGeoLocation(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	resolver, err := clientip.NewResolver([]string{"10.0.0.0/8"})
+	require.NoError(t, err)
+	shaper, err := shape.New(nil)
+	require.NoError(t, err)
+
+	rows := sqlmock.NewRows([]string{"IPAddress", "CountryCode", "Country", "City", "Latitude", "Longitude", "MysteryValue", "QualityScore", "QualityFlags"}).
+		AddRow("70.95.73.73", "TL", "Saudi Arabia", "Gradymouth", -49.16675918861615, -86.05920084416894, 2559997162, 100, "{}")
+	mock.ExpectQuery("SELECT (.+) FROM location WHERE ip_address = (.+)").WithArgs("70.95.73.73").WillReturnRows(rows)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/geolocation/me", nil)
+	req.RemoteAddr = "10.0.0.1:54321"
+	req.Header.Set("X-Forwarded-For", "70.95.73.73")
+	rec := httptest.NewRecorder()
+
+	GetMyGeoLocation(db, resolver, shaper, processes.BogonsAccept)(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var gotResult models.Location
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &gotResult))
+	assert.Equal(t, "Gradymouth", gotResult.City)
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetGeoLocation_reserved(t *testing.T) {
+	shaper, err := shape.New(nil)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		bogons     processes.BogonPolicy
+		ipAddress  string
+		rows       *sqlmock.Rows
+		wantStatus int
+		wantClass  string
+	}{
+		{
+			name:       "Reject without lookup",
+			bogons:     processes.BogonsReject,
+			ipAddress:  "192.168.1.10",
+			wantStatus: http.StatusUnprocessableEntity,
+			wantClass:  "private",
+		},
+		{
+			name:       "Tag not found",
+			bogons:     processes.BogonsTag,
+			ipAddress:  "2001:db8::1",
+			rows:       sqlmock.NewRows([]string{"IPAddress", "CountryCode", "Country", "City", "Latitude", "Longitude", "MysteryValue", "QualityScore", "QualityFlags"}),
+			wantStatus: http.StatusUnprocessableEntity,
+			wantClass:  "documentation",
+		},
+		{
+			name:      "Tag found",
+			bogons:    processes.BogonsTag,
+			ipAddress: "10.0.0.1",
+			rows: sqlmock.NewRows([]string{"IPAddress", "CountryCode", "Country", "City", "Latitude", "Longitude", "MysteryValue", "QualityScore", "QualityFlags"}).
+				AddRow("10.0.0.1", "NL", "Netherlands", "Amsterdam", 52.37, 4.89, 1, 70, "{reserved_address}"),
+			wantStatus: http.StatusOK,
+			wantClass:  "private",
+		},
+		{
+			name:       "Accept not found",
+			bogons:     processes.BogonsAccept,
+			ipAddress:  "127.0.0.1",
+			rows:       sqlmock.NewRows([]string{"IPAddress", "CountryCode", "Country", "City", "Latitude", "Longitude", "MysteryValue", "QualityScore", "QualityFlags"}),
+			wantStatus: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+			if tt.rows != nil {
+				mock.ExpectQuery("SELECT (.+) FROM location WHERE ip_address = (.+)").WithArgs(tt.ipAddress).WillReturnRows(tt.rows)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/api/geolocation/"+tt.ipAddress, nil)
+			rec := httptest.NewRecorder()
+			GetGeoLocation(db, shaper, tt.bogons)(rec, req)
+			require.Equal(t, tt.wantStatus, rec.Code)
+
+			if tt.wantClass != "" {
+				var got struct {
+					AddressClass string `json:"address_class"`
+				}
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+				assert.Equal(t, tt.wantClass, got.AddressClass)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,210 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"vio/internal/imports"
+	"vio/internal/models"
+
+	"github.com/gorilla/mux"
+)
+
+// importRequest is the JSON body of an import of a server-side path.
+type importRequest struct {
+	Path string `json:"path"`
+}
+
+// swagger:operation  POST /api/admin/imports CreateImport
+// Start an import of location data in the background.
+// The data is an uploaded CSV file (multipart/form-data field "file")
+// or a JSON object {"path": "..."} with a file or directory relative to the source directory of the server.
+// ---
+// consumes:
+// - application/json
+// - multipart/form-data
+// produces:
+// - application/json
+// parameters:
+//   - in: formData
+//     name: file
+//     description: CSV file with locations
+//     type: file
+//
+// responses:
+//
+//	'202':
+//	  description: Import started
+//	  schema:
+//	    $ref: '#/definitions/ImportJob'
+//	'400':
+//	  description: Invalid file or path
+//	'413':
+//	  description: File is too large
+//	'500':
+//	  description: Internal Server error
+func CreateImport(manager *imports.Manager, maxUploadSize int64) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+
+		var job models.ImportJob
+		var err error
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+			file, header, errForm := r.FormFile("file")
+			if errForm != nil {
+				writeRequestError(w, errForm)
+				return
+			}
+			defer file.Close()
+
+			job, err = manager.StartUpload(header.Filename, file)
+		} else {
+			var req importRequest
+			if errDecode := json.NewDecoder(r.Body).Decode(&req); errDecode != nil {
+				writeRequestError(w, errDecode)
+				return
+			}
+			if req.Path == "" {
+				http.Error(w, "Invalid request: file or path is required", http.StatusBadRequest)
+				return
+			}
+
+			job, err = manager.StartPath(req.Path)
+		}
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			switch {
+			case errors.As(err, &maxBytesErr):
+				http.Error(w, "File is too large", http.StatusRequestEntityTooLarge)
+			case errors.Is(err, imports.ErrInvalidPath):
+				http.Error(w, "Invalid path", http.StatusBadRequest)
+			default:
+				http.Error(w, "Failed to start import", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		w.Header().Set("Location", fmt.Sprintf("/api/admin/imports/%d", job.ID))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		_ = json.NewEncoder(w).Encode(job)
+	}
+}
+
+// swagger:operation  GET /api/admin/imports GetImports
+// Get imports started since the server start, the latest first.
+// ---
+// produces:
+// - application/json
+//
+// responses:
+//
+//	'200':
+//	  description: OK
+//	  schema:
+//	    type: array
+//	    items:
+//	      $ref: '#/definitions/ImportJob'
+func GetImports(manager *imports.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, manager.List())
+	}
+}
+
+// swagger:operation  GET /api/admin/imports/{id} GetImport
+// Get status and progress of the import.
+// ---
+// produces:
+// - application/json
+// parameters:
+//   - in: path
+//     name: id
+//     description: Import ID
+//     required: true
+//     type: integer
+//
+// responses:
+//
+//	'200':
+//	  description: OK
+//	  schema:
+//	    $ref: '#/definitions/ImportJob'
+//	'404':
+//	  description: Import not found
+func GetImport(manager *imports.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+		if err != nil {
+			http.Error(w, "Import not found", http.StatusNotFound)
+			return
+		}
+
+		job, err := manager.Get(id)
+		if err != nil {
+			http.Error(w, "Import not found", http.StatusNotFound)
+			return
+		}
+
+		writeJSON(w, job)
+	}
+}
+
+// swagger:operation  DELETE /api/admin/imports/{id} CancelImport
+// Cancel the running import, locations written before cancellation are kept.
+// ---
+// produces:
+// - application/json
+// parameters:
+//   - in: path
+//     name: id
+//     description: Import ID
+//     required: true
+//     type: integer
+//
+// responses:
+//
+//	'202':
+//	  description: Cancellation requested
+//	  schema:
+//	    $ref: '#/definitions/ImportJob'
+//	'404':
+//	  description: Import not found
+//	'409':
+//	  description: Import is already finished
+func CancelImport(manager *imports.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+		if err != nil {
+			http.Error(w, "Import not found", http.StatusNotFound)
+			return
+		}
+
+		job, err := manager.Cancel(id)
+		switch {
+		case errors.Is(err, imports.ErrJobNotFound):
+			http.Error(w, "Import not found", http.StatusNotFound)
+			return
+		case errors.Is(err, imports.ErrJobFinished):
+			http.Error(w, "Import is already finished", http.StatusConflict)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		_ = json.NewEncoder(w).Encode(job)
+	}
+}
+
+func writeRequestError(w http.ResponseWriter, err error) {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		http.Error(w, "File is too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	http.Error(w, "Invalid request: file or path is required", http.StatusBadRequest)
+}
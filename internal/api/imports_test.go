@@ -0,0 +1,140 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"vio/internal/imports"
+	"vio/internal/models"
+	"vio/internal/processes"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImports(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectPrepare(regexp.QuoteMeta(processes.SQLInsert)).ExpectExec().
+		WithArgs("70.95.73.73", "TL", "Saudi Arabia", "Gradymouth", -49.16675918861615, -86.05920084416894, 2559997162, nil, nil, 40, "country_mismatch,outside_country").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	manager := imports.NewManager(context.Background(), db, slog.New(slog.NewTextHandler(io.Discard, nil)), imports.Options{
+		SourceDir: t.TempDir(),
+		UploadDir: t.TempDir(),
+		Import:    processes.ImportOptions{Parallel: -1},
+	})
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/admin/imports", CreateImport(manager, 1024)).Methods("POST")
+	router.HandleFunc("/api/admin/imports/{id}", GetImport(manager)).Methods("GET")
+	router.HandleFunc("/api/admin/imports/{id}", CancelImport(manager)).Methods("DELETE")
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", "locations.csv")
+	require.NoError(t, err)
+	_, _ = io.WriteString(part, "ip_address,country_code,country,city,latitude,longitude,mystery_value\n"+
+		"70.95.73.73,TL,Saudi Arabia,Gradymouth,-49.16675918861615,-86.05920084416894,2559997162\n")
+	require.NoError(t, form.Close())
+
+	req := httptest.NewRequest(http.MethodPost, "/api/admin/imports", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusAccepted, rec.Code)
+	assert.Equal(t, "/api/admin/imports/1", rec.Header().Get("Location"))
+
+	manager.Wait()
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/admin/imports/1", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var job models.ImportJob
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&job))
+	assert.Equal(t, imports.StatusCompleted, job.Status)
+	assert.Equal(t, "upload:locations.csv", job.Source)
+	assert.Equal(t, models.ImportProgress{
+		Phase:         processes.PhaseDone,
+		FilesTotal:    1,
+		FilesRead:     1,
+		BytesTotal:    158,
+		BytesRead:     158,
+		File:          "upload.csv",
+		FileSize:      158,
+		FileBytesRead: 158,
+		RowsRead:      1,
+		RowsWritten:   1,
+		RowsToWrite:   1,
+	}, job.Progress)
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		body       string
+		wantStatus int
+	}{
+		{
+			name:       "Path outside of the source directory",
+			method:     http.MethodPost,
+			target:     "/api/admin/imports",
+			body:       `{"path": "../etc"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Missing path",
+			method:     http.MethodPost,
+			target:     "/api/admin/imports",
+			body:       `{}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Too large body",
+			method:     http.MethodPost,
+			target:     "/api/admin/imports",
+			body:       `{"path": "` + strings.Repeat("a", 2048) + `"}`,
+			wantStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:       "Unknown import",
+			method:     http.MethodGet,
+			target:     "/api/admin/imports/2",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Cancel finished import",
+			method:     http.MethodDelete,
+			target:     "/api/admin/imports/1",
+			wantStatus: http.StatusConflict,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body)))
+			assert.Equal(t, tt.wantStatus, rec.Code)
+		})
+	}
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,254 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"vio/internal/models"
+	"vio/internal/processes"
+	"vio/internal/shape"
+)
+
+// swagger:operation  GET /api/locations GetLocations
+// Search locations by country, city or geographic area.
+// ---
+// produces:
+// - application/json
+// parameters:
+//   - in: query
+//     name: country_code
+//     description: Country code
+//     type: string
+//   - in: query
+//     name: country
+//     description: Country name
+//     type: string
+//   - in: query
+//     name: city
+//     description: City name
+//     type: string
+//   - in: query
+//     name: min_lat
+//     description: Minimum latitude of the bounding box
+//     type: number
+//   - in: query
+//     name: max_lat
+//     description: Maximum latitude of the bounding box
+//     type: number
+//   - in: query
+//     name: min_lon
+//     description: Minimum longitude of the bounding box
+//     type: number
+//   - in: query
+//     name: max_lon
+//     description: Maximum longitude of the bounding box
+//     type: number
+//   - in: query
+//     name: lat
+//     description: Latitude of the radius search center
+//     type: number
+//   - in: query
+//     name: lon
+//     description: Longitude of the radius search center
+//     type: number
+//   - in: query
+//     name: radius_km
+//     description: Radius of the search in kilometers
+//     type: number
+//   - in: query
+//     name: min_quality
+//     description: Minimum quality score of the locations (0-100)
+//     type: integer
+//   - in: query
+//     name: cursor
+//     description: Cursor of the next page from the previous response
+//     type: string
+//   - in: query
+//     name: limit
+//     description: Page size (default 100, max 1000)
+//     type: integer
+//   - in: query
+//     name: fields
+//     description: Comma-separated list of fields to return
+//     type: string
+//   - in: query
+//     name: lang
+//     description: Language of the country name (BCP 47 tag)
+//     type: string
+//
+// responses:
+//
+//	'200':
+//	  description: OK
+//	  schema:
+//	    $ref: '#/definitions/LocationPage'
+//	'400':
+//	  description: Invalid query parameters
+//	'500':
+//	  description: Internal Server error
+//	'504':
+//	  description: Query timeout exceeded
+func GetLocations(db *sql.DB, shaper *shape.Shaper) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		filter, err := parseLocationFilter(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		sh, err := shaper.Parse(r.URL.Query(), models.Location{})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		page, err := processes.FindLocations(r.Context(), db, *filter)
+		if err != nil {
+			if errors.Is(err, processes.ErrInvalidCursor) {
+				http.Error(w, "Invalid cursor", http.StatusBadRequest)
+				return
+			}
+			writeQueryError(r.Context(), w, err, "Failed to retrieve locations")
+			return
+		}
+
+		items, err := shape.ApplyAll(sh, page.Items)
+		if err != nil {
+			http.Error(w, "Failed to encode locations", http.StatusInternalServerError)
+			return
+		}
+
+		writeJSON(w, shapedLocationPage{
+			Items:      items,
+			NextCursor: page.NextCursor,
+		})
+	}
+}
+
+// shapedLocationPage is models.LocationPage with the shaped items.
+type shapedLocationPage struct {
+	Items      []json.RawMessage `json:"items"`
+	NextCursor string            `json:"next_cursor,omitempty"`
+}
+
+func parseLocationFilter(query url.Values) (*models.LocationFilter, error) {
+	filter := &models.LocationFilter{
+		CountryCode: strings.ToUpper(strings.TrimSpace(query.Get("country_code"))),
+		Country:     strings.TrimSpace(query.Get("country")),
+		City:        strings.TrimSpace(query.Get("city")),
+		Cursor:      query.Get("cursor"),
+	}
+
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > processes.MaxSearchLimit {
+			return nil, fmt.Errorf("invalid limit: must be between 1 and %d", processes.MaxSearchLimit)
+		}
+		filter.Limit = limit
+	}
+
+	minQuality, err := processes.ParseMinQuality(query.Get("min_quality"))
+	if err != nil {
+		return nil, err
+	}
+	filter.MinQuality = minQuality
+
+	box, err := parseBoundingBox(query)
+	if err != nil {
+		return nil, err
+	}
+	circle, err := parseCircle(query)
+	if err != nil {
+		return nil, err
+	}
+	if box != nil && circle != nil {
+		return nil, errors.New("bounding box and radius can not be used together")
+	}
+	filter.BoundingBox = box
+	filter.Circle = circle
+
+	return filter, nil
+}
+
+func parseBoundingBox(query url.Values) (*models.BoundingBox, error) {
+	values, err := parseFloatParams(query, "min_lat", "max_lat", "min_lon", "max_lon")
+	if values == nil || err != nil {
+		return nil, err
+	}
+
+	box := &models.BoundingBox{
+		MinLatitude:  values[0],
+		MaxLatitude:  values[1],
+		MinLongitude: values[2],
+		MaxLongitude: values[3],
+	}
+	if !isValidLatitude(box.MinLatitude) || !isValidLatitude(box.MaxLatitude) || box.MinLatitude > box.MaxLatitude {
+		return nil, errors.New("invalid bounding box latitude")
+	}
+	if !isValidLongitude(box.MinLongitude) || !isValidLongitude(box.MaxLongitude) {
+		return nil, errors.New("invalid bounding box longitude")
+	}
+
+	return box, nil
+}
+
+func parseCircle(query url.Values) (*models.Circle, error) {
+	values, err := parseFloatParams(query, "lat", "lon", "radius_km")
+	if values == nil || err != nil {
+		return nil, err
+	}
+
+	circle := &models.Circle{
+		Latitude:  values[0],
+		Longitude: values[1],
+		RadiusKm:  values[2],
+	}
+	if !isValidLatitude(circle.Latitude) || !isValidLongitude(circle.Longitude) {
+		return nil, errors.New("invalid radius center")
+	}
+	if circle.RadiusKm <= 0 {
+		return nil, errors.New("invalid radius_km: must be positive")
+	}
+
+	return circle, nil
+}
+
+// parseFloatParams returns nil when none of the parameters are set
+// and an error when only some of them are set or can not be parsed.
+func parseFloatParams(query url.Values, names ...string) ([]float64, error) {
+	values := make([]float64, 0, len(names))
+	for _, name := range names {
+		value := query.Get(name)
+		if value == "" {
+			continue
+		}
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil || math.IsNaN(number) || math.IsInf(number, 0) {
+			return nil, fmt.Errorf("invalid %s: must be a number", name)
+		}
+		values = append(values, number)
+	}
+
+	switch len(values) {
+	case 0:
+		return nil, nil
+	case len(names):
+		return values, nil
+	default:
+		return nil, fmt.Errorf("parameters %s must be set together", strings.Join(names, ", "))
+	}
+}
+
+func isValidLatitude(latitude float64) bool {
+	return latitude >= -90 && latitude <= 90
+}
+
+func isValidLongitude(longitude float64) bool {
+	return longitude >= -180 && longitude <= 180
+}
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"net/url"
+	"testing"
+
+	"vio/internal/models"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseLocationFilter(t *testing.T) {
+	tests := []struct {
+		name            string
+		query           string
+		wantResult      *models.LocationFilter
+		wantErrorString string
+	}{
+		{
+			name:  "Country code and limit",
+			query: "country_code=si&limit=20",
+			wantResult: &models.LocationFilter{
+				CountryCode: "SI",
+				Limit:       20,
+			},
+		},
+		{
+			name:  "Minimum quality",
+			query: "min_quality=80",
+			wantResult: &models.LocationFilter{
+				MinQuality: 80,
+			},
+		},
+		{
+			name:            "Invalid minimum quality",
+			query:           "min_quality=101",
+			wantErrorString: `invalid min_quality "101": must be between 0 and 100`,
+		},
+		{
+			name:  "Bounding box",
+			query: "min_lat=-10&max_lat=10&min_lon=-20&max_lon=20",
+			wantResult: &models.LocationFilter{
+				BoundingBox: &models.BoundingBox{
+					MinLatitude:  -10,
+					MaxLatitude:  10,
+					MinLongitude: -20,
+					MaxLongitude: 20,
+				},
+			},
+		},
+		{
+			name:  "Radius",
+			query: "lat=52.37&lon=4.89&radius_km=25",
+			wantResult: &models.LocationFilter{
+				Circle: &models.Circle{
+					Latitude:  52.37,
+					Longitude: 4.89,
+					RadiusKm:  25,
+				},
+			},
+		},
+		{
+			name:            "Incomplete bounding box",
+			query:           "min_lat=-10&max_lat=10",
+			wantErrorString: "parameters min_lat, max_lat, min_lon, max_lon must be set together",
+		},
+		{
+			name:            "Bounding box and radius",
+			query:           "min_lat=-10&max_lat=10&min_lon=-20&max_lon=20&lat=0&lon=0&radius_km=1",
+			wantErrorString: "bounding box and radius can not be used together",
+		},
+		{
+			name:            "Invalid latitude",
+			query:           "lat=91&lon=0&radius_km=1",
+			wantErrorString: "invalid radius center",
+		},
+		{
+			name:            "Invalid radius",
+			query:           "lat=0&lon=0&radius_km=NaN",
+			wantErrorString: "invalid radius_km: must be a number",
+		},
+		{
+			name:            "Invalid limit",
+			query:           "limit=0",
+			wantErrorString: "invalid limit: must be between 1 and 1000",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			query, err := url.ParseQuery(tt.query)
+			assert.NoError(t, err)
+
+			got, err := parseLocationFilter(query)
+			if tt.wantErrorString != "" {
+				assert.EqualError(t, err, tt.wantErrorString)
+				return
+			}
+			assert.NoError(t, err)
+
+			diff := cmp.Diff(tt.wantResult, got)
+			if diff != "" {
+				t.Fatal("result mismatch\n", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"vio/internal/lib/geo"
+	"vio/internal/models"
+	"vio/internal/processes"
+	"vio/internal/shape"
+)
+
+// DefaultNearbyLimit is the number of nearby locations returned when the limit is not set.
+const DefaultNearbyLimit = 10
+
+// swagger:operation  GET /api/geolocation/nearby GetNearbyLocations
+// Get locations closest to the point.
+// ---
+// produces:
+// - application/json
+// parameters:
+//   - in: query
+//     name: lat
+//     description: Latitude of the point
+//     required: true
+//     type: number
+//   - in: query
+//     name: lon
+//     description: Longitude of the point
+//     required: true
+//     type: number
+//   - in: query
+//     name: radius_km
+//     description: Maximum distance in kilometers (not limited by default)
+//     type: number
+//   - in: query
+//     name: limit
+//     description: Maximum number of locations (default 10, max 1000)
+//     type: integer
+//   - in: query
+//     name: fields
+//     description: Comma-separated list of fields to return
+//     type: string
+//   - in: query
+//     name: lang
+//     description: Language of the country name (BCP 47 tag)
+//     type: string
+//
+// responses:
+//
+//	'200':
+//	  description: OK
+//	  schema:
+//	    type: array
+//	    items:
+//	      $ref: '#/definitions/NearbyLocation'
+//	'400':
+//	  description: Invalid query parameters
+//	'503':
+//	  description: Spatial index is not ready
+func GetNearbyLocations(index *processes.SpatialIndex, shaper *shape.Shaper) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		point, radiusKm, limit, err := parseNearbyQuery(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		sh, err := shaper.Parse(r.URL.Query(), models.NearbyLocation{})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		locations, err := index.Nearby(point, radiusKm, limit)
+		if err != nil {
+			if errors.Is(err, processes.ErrSpatialIndexNotReady) {
+				http.Error(w, "Spatial index is not ready", http.StatusServiceUnavailable)
+				return
+			}
+			http.Error(w, "Failed to retrieve locations", http.StatusInternalServerError)
+			return
+		}
+
+		items, err := shape.ApplyAll(sh, locations)
+		if err != nil {
+			http.Error(w, "Failed to encode locations", http.StatusInternalServerError)
+			return
+		}
+
+		writeJSON(w, items)
+	}
+}
+
+func parseNearbyQuery(query url.Values) (point geo.Point, radiusKm float64, limit int, err error) {
+	values, err := parseFloatParams(query, "lat", "lon")
+	if err != nil {
+		return point, 0, 0, err
+	}
+	if values == nil {
+		return point, 0, 0, errors.New("parameters lat, lon are required")
+	}
+	point = geo.Point{Latitude: values[0], Longitude: values[1]}
+	if !isValidLatitude(point.Latitude) || !isValidLongitude(point.Longitude) {
+		return point, 0, 0, errors.New("invalid point coordinates")
+	}
+
+	if values, err = parseFloatParams(query, "radius_km"); err != nil {
+		return point, 0, 0, err
+	}
+	if values != nil {
+		radiusKm = values[0]
+		if radiusKm <= 0 {
+			return point, 0, 0, errors.New("invalid radius_km: must be positive")
+		}
+	}
+
+	limit = DefaultNearbyLimit
+	if value := query.Get("limit"); value != "" {
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > processes.MaxSearchLimit {
+			return point, 0, 0, fmt.Errorf("invalid limit: must be between 1 and %d", processes.MaxSearchLimit)
+		}
+	}
+
+	return point, radiusKm, limit, nil
+}
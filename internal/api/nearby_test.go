@@ -0,0 +1,91 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"vio/internal/models"
+	"vio/internal/processes"
+	"vio/internal/shape"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetNearbyLocations(t *testing.T) {
+	shaper, err := shape.New(nil)
+	require.NoError(t, err)
+	index := processes.NewSpatialIndex()
+	handler := GetNearbyLocations(index, shaper)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/geolocation/nearby?lat=52.09&lon=5.12", nil)
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+	index.Set([]models.Location{
+		{IPAddress: "1.1.1.1", City: "Amsterdam", Latitude: 52.3676, Longitude: 4.9041},
+		{IPAddress: "2.2.2.2", City: "Berlin", Latitude: 52.5200, Longitude: 13.4050},
+		{IPAddress: "3.3.3.3", City: "New York", Latitude: 40.7128, Longitude: -74.0060},
+	})
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantIPs    []string
+	}{
+		{
+			name:       "Closest locations",
+			query:      "lat=52.09&lon=5.12&limit=2",
+			wantStatus: http.StatusOK,
+			wantIPs:    []string{"1.1.1.1", "2.2.2.2"},
+		},
+		{
+			name:       "Within radius",
+			query:      "lat=52.09&lon=5.12&radius_km=100",
+			wantStatus: http.StatusOK,
+			wantIPs:    []string{"1.1.1.1"},
+		},
+		{
+			name:       "Missing point",
+			query:      "radius_km=100",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Invalid radius",
+			query:      "lat=52.09&lon=5.12&radius_km=-1",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Invalid limit",
+			query:      "lat=52.09&lon=5.12&limit=abc",
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(http.MethodGet, "/api/geolocation/nearby?"+tt.query, nil)
+			rec := httptest.NewRecorder()
+			handler(rec, req)
+
+			require.Equal(t, tt.wantStatus, rec.Code)
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var got []models.NearbyLocation
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+			gotIPs := make([]string, 0, len(got))
+			for _, loc := range got {
+				gotIPs = append(gotIPs, loc.IPAddress)
+			}
+			assert.Equal(t, tt.wantIPs, gotIPs)
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"vio/internal/auth"
+	"vio/internal/lib/clientip"
+	"vio/internal/lib/ratelimit"
+)
+
+// RateLimiter limits requests per client IP address and per API key.
+type RateLimiter struct {
+	resolver *clientip.Resolver
+	byIP     *ratelimit.Limiter
+	byKey    *ratelimit.Limiter
+}
+
+func NewRateLimiter(resolver *clientip.Resolver, byIP, byKey *ratelimit.Limiter) *RateLimiter {
+	return &RateLimiter{
+		resolver: resolver,
+		byIP:     byIP,
+		byKey:    byKey,
+	}
+}
+
+// LimitByIP is a middleware limiting requests per client IP address.
+// It must run before authentication, so invalid keys do not reach the database unlimited.
+func (l *RateLimiter) LimitByIP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		addr, err := l.resolver.ClientIP(r)
+		if err != nil {
+			http.Error(w, "Client IP address can not be determined", http.StatusBadRequest)
+			return
+		}
+
+		if !allow(w, l.byIP, "ip:"+addr.String()) {
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// allowKey limits requests per API key, the authenticator checks it before the daily quota is consumed,
+// so rejected requests are not counted in the usage of the key.
+func (l *RateLimiter) allowKey(w http.ResponseWriter, key *auth.APIKey) bool {
+	return allow(w, l.byKey, "key:"+strconv.FormatInt(key.ID, 10))
+}
+
+// allow sets the rate limit headers and writes 429 Too Many Requests when the request is not allowed.
+func allow(w http.ResponseWriter, limiter *ratelimit.Limiter, key string) bool {
+	result := limiter.Allow(key)
+
+	w.Header().Set("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+	w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	w.Header().Set("X-RateLimit-Reset", strconv.Itoa(ratelimit.CeilSeconds(result.Reset)))
+
+	if !result.Allowed {
+		w.Header().Set("Retry-After", strconv.Itoa(ratelimit.CeilSeconds(result.RetryAfter)))
+		http.Error(w, "Too many requests", http.StatusTooManyRequests)
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"vio/internal/auth"
+	"vio/internal/lib/clientip"
+	"vio/internal/lib/ratelimit"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiter(t *testing.T) {
+	resolver, err := clientip.NewResolver(nil)
+	require.NoError(t, err)
+
+	limiter := NewRateLimiter(resolver, ratelimit.New(1, 2), ratelimit.New(1, 1))
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	byIP := limiter.LimitByIP(ok)
+	send := func(handler http.Handler, remoteAddr string, key *auth.APIKey) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/geolocation/70.95.73.73", nil)
+		req.RemoteAddr = remoteAddr
+		if key != nil {
+			req = req.WithContext(auth.WithAPIKey(req.Context(), key))
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := send(byIP, "70.95.73.73:1000", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "2", rec.Header().Get("X-RateLimit-Limit"))
+	assert.Equal(t, "1", rec.Header().Get("X-RateLimit-Remaining"))
+
+	assert.Equal(t, http.StatusOK, send(byIP, "70.95.73.73:1001", nil).Code)
+
+	rec = send(byIP, "70.95.73.73:1002", nil)
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "1", rec.Header().Get("Retry-After"))
+	assert.Equal(t, "0", rec.Header().Get("X-RateLimit-Remaining"))
+
+	// Another client has its own limit.
+	assert.Equal(t, http.StatusOK, send(byIP, "125.159.20.54:1000", nil).Code)
+
+	// The limit per API key is shared by all addresses of the key.
+	key := &auth.APIKey{ID: 1}
+	rec = httptest.NewRecorder()
+	assert.True(t, limiter.allowKey(rec, key))
+	rec = httptest.NewRecorder()
+	assert.False(t, limiter.allowKey(rec, key))
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.True(t, limiter.allowKey(httptest.NewRecorder(), &auth.APIKey{ID: 2}))
+}
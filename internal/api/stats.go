@@ -0,0 +1,110 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"vio/internal/models"
+	"vio/internal/processes"
+	"vio/internal/shape"
+)
+
+type statisticsFunc func(ctx context.Context, db *sql.DB, top int) ([]models.LocationStatistics, error)
+
+// swagger:operation  GET /api/stats/countries GetCountryStatistics
+// Get statistics of IP addresses per country.
+// ---
+// produces:
+// - application/json
+// parameters:
+//   - in: query
+//     name: top
+//     description: Return only the top N countries by number of IP addresses
+//     type: integer
+//
+// responses:
+//
+//	'200':
+//	  description: OK
+//	  schema:
+//	    type: array
+//	    items:
+//	      $ref: '#/definitions/LocationStatistics'
+//	'400':
+//	  description: Invalid query parameters
+//	'500':
+//	  description: Internal Server error
+//	'504':
+//	  description: Query timeout exceeded
+func GetCountryStatistics(db *sql.DB, shaper *shape.Shaper) http.HandlerFunc {
+	return getStatistics(db, shaper, processes.GetCountryStatistics)
+}
+
+// swagger:operation  GET /api/stats/cities GetCityStatistics
+// Get statistics of IP addresses per city.
+// ---
+// produces:
+// - application/json
+// parameters:
+//   - in: query
+//     name: top
+//     description: Return only the top N cities by number of IP addresses
+//     type: integer
+//
+// responses:
+//
+//	'200':
+//	  description: OK
+//	  schema:
+//	    type: array
+//	    items:
+//	      $ref: '#/definitions/LocationStatistics'
+//	'400':
+//	  description: Invalid query parameters
+//	'500':
+//	  description: Internal Server error
+//	'504':
+//	  description: Query timeout exceeded
+func GetCityStatistics(db *sql.DB, shaper *shape.Shaper) http.HandlerFunc {
+	return getStatistics(db, shaper, processes.GetCityStatistics)
+}
+
+// getStatistics returns the statistics without the fields hidden by the response fields of the service.
+func getStatistics(db *sql.DB, shaper *shape.Shaper, fn statisticsFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		top, err := parseTop(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		statistics, err := fn(r.Context(), db, top)
+		if err != nil {
+			writeQueryError(r.Context(), w, err, "Failed to retrieve statistics")
+			return
+		}
+		for i := range statistics {
+			shaper.HideStatistics(&statistics[i])
+		}
+
+		writeJSON(w, statistics)
+	}
+}
+
+func parseTop(query url.Values) (int, error) {
+	value := query.Get("top")
+	if value == "" {
+		return 0, nil
+	}
+
+	top, err := strconv.Atoi(value)
+	if err != nil || top < 1 {
+		return 0, errors.New("invalid top: must be a positive integer")
+	}
+
+	return top, nil
+}
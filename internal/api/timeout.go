@@ -0,0 +1,39 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// QueryTimeout returns a middleware cancelling the context of requests running longer than the timeout,
+// so their database queries are cancelled. The timeout 0 disables it.
+func QueryTimeout(timeout time.Duration) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		if timeout <= 0 {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// writeQueryError answers 504 Gateway Timeout when the query is cancelled by the query timeout,
+// other errors are answered with 500 and the message.
+func writeQueryError(ctx context.Context, w http.ResponseWriter, err error, message string) {
+	// The driver may return the error of the cancelled statement instead of the context error.
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		http.Error(w, "Query timeout exceeded", http.StatusGatewayTimeout)
+		return
+	}
+
+	http.Error(w, message, http.StatusInternalServerError)
+}
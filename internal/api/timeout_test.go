@@ -0,0 +1,67 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vio/internal/processes"
+	"vio/internal/shape"
+)
+
+func TestQueryTimeout(t *testing.T) {
+	shaper, err := shape.New(nil)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		timeout    time.Duration
+		wantStatus int
+	}{
+		{
+			name:       "Query is cancelled",
+			timeout:    20 * time.Millisecond,
+			wantStatus: http.StatusGatewayTimeout,
+		},
+		{
+			name:       "Timeout is disabled",
+			wantStatus: http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+			rows := sqlmock.NewRows([]string{"IPAddress", "CountryCode", "Country", "City", "Latitude", "Longitude", "MysteryValue", "QualityScore", "QualityFlags"}).
+				AddRow("70.95.73.73", "NL", "Netherlands", "Amsterdam", 52.37, 4.89, 1, 100, "{}")
+			mock.ExpectQuery("SELECT (.+) FROM location WHERE ip_address = (.+)").
+				WithArgs("70.95.73.73").
+				WillDelayFor(200 * time.Millisecond).
+				WillReturnRows(rows)
+
+			router := mux.NewRouter()
+			router.HandleFunc("/api/geolocation/{ip_address}", GetGeoLocation(db, shaper, processes.BogonsAccept))
+			router.Use(QueryTimeout(tt.timeout))
+
+			req := httptest.NewRequest(http.MethodGet, "/api/geolocation/70.95.73.73", nil)
+			rec := httptest.NewRecorder()
+			started := time.Now()
+			router.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			if tt.timeout > 0 {
+				assert.Less(t, time.Since(started), 200*time.Millisecond, "query is not cancelled")
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Scope is a permission granted to an API key.
+type Scope string
+
+const (
+	// ScopeLookup allows lookups of single locations.
+	ScopeLookup Scope = "lookup"
+	// ScopeBatch allows searches and statistics returning many locations.
+	ScopeBatch Scope = "batch"
+	// ScopeAdmin allows administrative operations and implies all other scopes.
+	ScopeAdmin Scope = "admin"
+)
+
+// APIKey represents an API key without the secret.
+type APIKey struct {
+	ID         int64      `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []Scope    `json:"scopes"`
+	DailyQuota int64      `json:"daily_quota"`
+	CreatedAt  time.Time  `json:"created_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// HasScope reports whether the key is granted the scope.
+func (k *APIKey) HasScope(scope Scope) bool {
+	for _, s := range k.Scopes {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ParseScopes parses a comma-separated list of scopes.
+func ParseScopes(value string) ([]Scope, error) {
+	var scopes []Scope
+	for _, item := range strings.Split(value, ",") {
+		scope := Scope(strings.TrimSpace(item))
+		switch scope {
+		case "":
+			continue
+		case ScopeLookup, ScopeBatch, ScopeAdmin:
+			scopes = append(scopes, scope)
+		default:
+			return nil, fmt.Errorf("unknown scope %q", scope)
+		}
+	}
+	if len(scopes) == 0 {
+		return nil, fmt.Errorf("at least one scope is required")
+	}
+
+	return scopes, nil
+}
+
+type contextKey struct{}
+
+// WithAPIKey returns a copy of the context with the authenticated key.
+func WithAPIKey(ctx context.Context, key *APIKey) context.Context {
+	return context.WithValue(ctx, contextKey{}, key)
+}
+
+// FromContext returns the authenticated key of the request, or nil.
+func FromContext(ctx context.Context) *APIKey {
+	key, _ := ctx.Value(contextKey{}).(*APIKey)
+	return key
+}
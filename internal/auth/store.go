@@ -0,0 +1,181 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+const (
+	keyPrefix    = "vio_"
+	keyBytes     = 32
+	keyPrefixLen = 12
+)
+
+var (
+	// ErrInvalidKey is returned when the key does not exist or has been revoked.
+	ErrInvalidKey = errors.New("invalid API key")
+	// ErrKeyNotFound is returned when the key with the given ID does not exist.
+	ErrKeyNotFound = errors.New("API key not found")
+)
+
+var SQLInsertAPIKey = `INSERT INTO api_key (name, key_prefix, key_hash, scopes, daily_quota)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, created_at`
+
+var SQLSelectAPIKeyByHash = `SELECT id, name, key_prefix, scopes, daily_quota, created_at, revoked_at
+FROM api_key WHERE key_hash = $1 AND revoked_at IS NULL`
+
+var SQLSelectAPIKeys = `SELECT id, name, key_prefix, scopes, daily_quota, created_at, revoked_at
+FROM api_key ORDER BY id`
+
+var SQLRevokeAPIKey = `UPDATE api_key SET revoked_at = now() WHERE id = $1 AND revoked_at IS NULL`
+
+var SQLConsumeQuota = `INSERT INTO api_key_usage (api_key_id, day, requests)
+VALUES ($1, (now() AT TIME ZONE 'UTC')::date, 1)
+ON CONFLICT (api_key_id, day) DO UPDATE
+SET requests = api_key_usage.requests + 1
+RETURNING requests`
+
+// Store keeps API keys in the database, only SHA-256 hashes of the secrets are stored.
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// Issue creates a new key and returns its secret, the secret can not be restored later.
+func (s *Store) Issue(ctx context.Context, name string, scopes []Scope, dailyQuota int64) (string, *APIKey, error) {
+	secret, err := generateSecret()
+	if err != nil {
+		return "", nil, err
+	}
+
+	key := &APIKey{
+		Name:       name,
+		Prefix:     secret[:keyPrefixLen],
+		Scopes:     scopes,
+		DailyQuota: dailyQuota,
+	}
+	err = s.db.QueryRowContext(ctx, SQLInsertAPIKey,
+		key.Name, key.Prefix, hashSecret(secret), pq.Array(scopesToStrings(scopes)), key.DailyQuota,
+	).Scan(&key.ID, &key.CreatedAt)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return secret, key, nil
+}
+
+// Revoke revokes the key, revoked keys can not be used anymore.
+func (s *Store) Revoke(ctx context.Context, id int64) error {
+	result, err := s.db.ExecContext(ctx, SQLRevokeAPIKey, id)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrKeyNotFound
+	}
+
+	return nil
+}
+
+// List returns all keys including the revoked ones.
+func (s *Store) List(ctx context.Context) ([]APIKey, error) {
+	rows, err := s.db.QueryContext(ctx, SQLSelectAPIKeys)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := make([]APIKey, 0)
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, *key)
+	}
+
+	return keys, rows.Err()
+}
+
+// Authenticate returns the active key matching the secret.
+func (s *Store) Authenticate(ctx context.Context, secret string) (*APIKey, error) {
+	if !strings.HasPrefix(secret, keyPrefix) {
+		return nil, ErrInvalidKey
+	}
+
+	key, err := scanAPIKey(s.db.QueryRowContext(ctx, SQLSelectAPIKeyByHash, hashSecret(secret)))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInvalidKey
+	}
+
+	return key, err
+}
+
+// ConsumeQuota counts a request of the key and returns the number of requests made today (UTC).
+func (s *Store) ConsumeQuota(ctx context.Context, id int64) (int64, error) {
+	var requests int64
+	err := s.db.QueryRowContext(ctx, SQLConsumeQuota, id).Scan(&requests)
+
+	return requests, err
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanAPIKey(row scanner) (*APIKey, error) {
+	var key APIKey
+	var scopes []string
+	var revokedAt sql.NullTime
+	err := row.Scan(&key.ID, &key.Name, &key.Prefix, pq.Array(&scopes), &key.DailyQuota, &key.CreatedAt, &revokedAt)
+	if err != nil {
+		return nil, err
+	}
+	for _, scope := range scopes {
+		key.Scopes = append(key.Scopes, Scope(scope))
+	}
+	if revokedAt.Valid {
+		key.RevokedAt = &revokedAt.Time
+	}
+
+	return &key, nil
+}
+
+func generateSecret() (string, error) {
+	data := make([]byte, keyBytes)
+	if _, err := rand.Read(data); err != nil {
+		return "", err
+	}
+
+	return keyPrefix + base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func scopesToStrings(scopes []Scope) []string {
+	result := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		result = append(result, string(scope))
+	}
+
+	return result
+}
@@ -0,0 +1,100 @@
+package auth
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore_IssueAndAuthenticate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := NewStore(db)
+	ctx := context.Background()
+	createdAt := time.Date(2023, 11, 20, 10, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(regexp.QuoteMeta(SQLInsertAPIKey)).
+		WithArgs("dashboard", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), int64(1000)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, createdAt))
+
+	secret, key, err := store.Issue(ctx, "dashboard", []Scope{ScopeLookup, ScopeBatch}, 1000)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(secret, keyPrefix))
+	assert.Equal(t, secret[:keyPrefixLen], key.Prefix)
+	assert.Equal(t, int64(1), key.ID)
+
+	columns := []string{"id", "name", "key_prefix", "scopes", "daily_quota", "created_at", "revoked_at"}
+	mock.ExpectQuery(regexp.QuoteMeta(SQLSelectAPIKeyByHash)).
+		WithArgs(hashSecret(secret)).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(1, "dashboard", key.Prefix, "{lookup,batch}", 1000, createdAt, nil))
+
+	got, err := store.Authenticate(ctx, secret)
+	require.NoError(t, err)
+	assert.Equal(t, []Scope{ScopeLookup, ScopeBatch}, got.Scopes)
+	assert.Nil(t, got.RevokedAt)
+
+	mock.ExpectQuery(regexp.QuoteMeta(SQLSelectAPIKeyByHash)).
+		WithArgs(hashSecret(secret)).
+		WillReturnRows(sqlmock.NewRows(columns))
+
+	_, err = store.Authenticate(ctx, secret)
+	assert.ErrorIs(t, err, ErrInvalidKey)
+
+	_, err = store.Authenticate(ctx, "not a key")
+	assert.ErrorIs(t, err, ErrInvalidKey)
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestStore_Revoke(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := NewStore(db)
+
+	mock.ExpectExec(regexp.QuoteMeta(SQLRevokeAPIKey)).WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(SQLRevokeAPIKey)).WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	assert.NoError(t, store.Revoke(context.Background(), 1))
+	assert.ErrorIs(t, store.Revoke(context.Background(), 2), ErrKeyNotFound)
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestParseScopes(t *testing.T) {
+	scopes, err := ParseScopes("lookup, batch")
+	require.NoError(t, err)
+	assert.Equal(t, []Scope{ScopeLookup, ScopeBatch}, scopes)
+
+	_, err = ParseScopes("lookup,write")
+	assert.EqualError(t, err, `unknown scope "write"`)
+
+	_, err = ParseScopes("")
+	assert.Error(t, err)
+}
+
+func TestAPIKey_HasScope(t *testing.T) {
+	key := &APIKey{Scopes: []Scope{ScopeLookup}}
+	assert.True(t, key.HasScope(ScopeLookup))
+	assert.False(t, key.HasScope(ScopeBatch))
+
+	admin := &APIKey{Scopes: []Scope{ScopeAdmin}}
+	assert.True(t, admin.HasScope(ScopeBatch))
+}
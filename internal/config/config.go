@@ -1,9 +1,11 @@
 package config
 
 import (
+	"fmt"
 	"log"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
 )
@@ -13,6 +15,90 @@ type Config struct {
 	Version   string `yaml:"version" env-default:"unknown"`
 	Port      int    `yaml:"port" env-default:"8085"`
 	DBConnect string `yaml:"db_connect" env-default:""`
+	// Database is the connection pool and the timeouts of the database.
+	Database DatabaseConfig `yaml:"database"`
+	// SpatialIndexRefresh is the interval of reloading locations for the nearby search, 0 disables reloading.
+	SpatialIndexRefresh time.Duration `yaml:"spatial_index_refresh" env-default:"0"`
+	// TrustedProxies are CIDRs or IP addresses of proxies allowed to set client IP headers.
+	TrustedProxies []string `yaml:"trusted_proxies" env-default:""`
+	// ResponseFields is the allow-list of location fields exposed by the API, empty means all fields.
+	ResponseFields []string `yaml:"response_fields" env-default:""`
+	// AuthEnabled requires API keys for all API requests.
+	AuthEnabled bool `yaml:"auth_enabled" env-default:"false"`
+	// Bogons is the handling of special-purpose addresses at imports and lookups: accept, reject or tag.
+	Bogons    string          `yaml:"bogons" env-default:"accept"`
+	RateLimit RateLimitConfig `yaml:"rate_limit"`
+	TLS       TLSConfig       `yaml:"tls"`
+	GRPC      GRPCConfig      `yaml:"grpc"`
+	Imports   ImportsConfig   `yaml:"imports"`
+}
+
+// DatabaseConfig connection pool and timeouts, zero values keep the defaults of database/sql and the server.
+type DatabaseConfig struct {
+	MaxOpenConns int `yaml:"max_open_conns" env-default:"0"`
+	MaxIdleConns int `yaml:"max_idle_conns" env-default:"0"`
+	// ConnMaxLifetime closes connections older than it, so they are balanced after database failovers.
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" env-default:"0"`
+	// StatementTimeout aborts statements running longer on the server, imports included.
+	StatementTimeout time.Duration `yaml:"statement_timeout" env-default:"0"`
+	// QueryTimeout cancels the queries of an API request running longer.
+	QueryTimeout time.Duration `yaml:"query_timeout" env-default:"0"`
+}
+
+// RateLimitConfig token bucket limits of API requests.
+type RateLimitConfig struct {
+	Enabled bool `yaml:"enabled" env-default:"false"`
+	// IPRate is the number of requests per second allowed for a client IP address.
+	IPRate  float64 `yaml:"ip_rate" env-default:"10"`
+	IPBurst int     `yaml:"ip_burst" env-default:"20"`
+	// KeyRate is the number of requests per second allowed for an API key.
+	KeyRate  float64 `yaml:"key_rate" env-default:"50"`
+	KeyBurst int     `yaml:"key_burst" env-default:"100"`
+}
+
+// TLSConfig HTTPS serving, the certificate files are reloaded when they change.
+type TLSConfig struct {
+	Enabled  bool   `yaml:"enabled" env-default:"false"`
+	CertFile string `yaml:"cert_file" env-default:""`
+	KeyFile  string `yaml:"key_file" env-default:""`
+	// MinVersion is one of: 1.0, 1.1, 1.2, 1.3.
+	MinVersion string `yaml:"min_version" env-default:"1.2"`
+	// CipherSuites for TLS 1.0-1.2, empty means Go defaults.
+	CipherSuites []string `yaml:"cipher_suites" env-default:""`
+	// ClientCAFile enables verification of client certificates (mutual TLS).
+	ClientCAFile string `yaml:"client_ca_file" env-default:""`
+	// ClientAuth is one of: none, request, require_any, verify_if_given, require_and_verify.
+	ClientAuth string `yaml:"client_auth" env-default:""`
+}
+
+// GRPCConfig gRPC API served on a separate port, it uses the TLS settings of the REST API.
+type GRPCConfig struct {
+	Enabled bool `yaml:"enabled" env-default:"false"`
+	Port    int  `yaml:"port" env-default:"9087"`
+}
+
+// ImportsConfig imports of location data started by the admin API.
+type ImportsConfig struct {
+	// SourceDir is the directory server-side import paths are resolved in.
+	SourceDir string `yaml:"source_dir" env-default:"data_source"`
+	// UploadDir keeps uploaded files during the import, empty means the system temporary directory.
+	UploadDir string `yaml:"upload_dir" env-default:""`
+	// MaxUploadSize is the maximum size of an uploaded file in bytes.
+	MaxUploadSize int64 `yaml:"max_upload_size" env-default:"104857600"`
+	// KeepFinished is the number of the latest finished imports kept in memory.
+	KeepFinished int `yaml:"keep_finished" env-default:"100"`
+	// Parallel is the number of goroutines writing locations, -1 = off, 0 = count of CPU cores, -2 = adaptive.
+	Parallel int `yaml:"parallel" env-default:"0"`
+	// BatchSize is the number of locations written with one statement, 1 = one by one.
+	BatchSize int `yaml:"batch_size" env-default:"1"`
+	// PoolReserve is the number of database connections left to the API, the adaptive workers use the rest.
+	PoolReserve int `yaml:"pool_reserve" env-default:"10"`
+	// Countries is the handling of records whose country code and name disagree: off, flag, correct or reject.
+	Countries string `yaml:"countries" env-default:"off"`
+	// Coordinates is the handling of records whose coordinates are outside of the country: off, flag or reject.
+	Coordinates string `yaml:"coordinates" env-default:"off"`
+	// MinQuality discards locations with a lower quality score (0-100), 0 imports all locations.
+	MinQuality int `yaml:"min_quality" env-default:"0"`
 }
 
 func MustLoad(name string) *Config {
@@ -32,3 +118,105 @@ func MustLoad(name string) *Config {
 
 	return &cfg
 }
+
+// LoaderConfig imports of the CLI loader, flags given on the command line override its values.
+type LoaderConfig struct {
+	DBConnect string `yaml:"db_connect" env:"LOADER_DB_CONNECT" env-default:"host=localhost port=25432 dbname=postgres user=postgres password=postgres client_encoding=UTF8 sslmode=disable"`
+	// Profile is used when no profile is selected by the -profile flag, empty uses the import section.
+	Profile string        `yaml:"profile" env:"LOADER_PROFILE" env-default:""`
+	Import  ImportProfile `yaml:"import"`
+	// Profiles are the settings of vendor feeds, values set in a profile override the import section,
+	// the LOADER_* environment variables override both.
+	Profiles map[string]ImportProfile `yaml:"profiles"`
+}
+
+// ImportProfile settings of importing a source of location data.
+type ImportProfile struct {
+	// Source is the file or the directory of the source files.
+	Source string `yaml:"source" env:"LOADER_SOURCE" env-default:"data_source"`
+	// Format of the source files: csv or tsv.
+	Format string `yaml:"format" env:"LOADER_FORMAT" env-default:"csv"`
+	// Columns maps location fields (ip_address, country_code, ...) to header columns, empty reads the default layout.
+	Columns map[string]string `yaml:"columns"`
+	// Duplicates is the handling of repeated IP addresses: last or first record wins.
+	Duplicates string `yaml:"duplicates" env:"LOADER_DUPLICATES" env-default:"last"`
+	// Parallel is the number of goroutines writing locations: -1 = off, 0 = count of CPU cores, auto = adaptive.
+	Parallel string `yaml:"parallel" env:"LOADER_PARALLEL" env-default:"0"`
+	// BatchSize is the number of locations written with one statement, 1 = one by one.
+	BatchSize int `yaml:"batch_size" env:"LOADER_BATCH_SIZE" env-default:"1"`
+	// Bogons is the handling of special-purpose addresses: accept, reject or tag.
+	Bogons string `yaml:"bogons" env:"LOADER_BOGONS" env-default:"accept"`
+	// Countries is the handling of records whose country code and name disagree: off, flag, correct or reject.
+	Countries string `yaml:"countries" env:"LOADER_COUNTRIES" env-default:"off"`
+	// Coordinates is the handling of records whose coordinates are outside of the country: off, flag or reject.
+	Coordinates string `yaml:"coordinates" env:"LOADER_COORDINATES" env-default:"off"`
+	// MinQuality discards locations with a lower quality score (0-100), 0 imports all locations.
+	MinQuality string `yaml:"min_quality" env:"LOADER_MIN_QUALITY" env-default:"0"`
+}
+
+// LoadLoader reads the loader config from the file, only from the environment when the path is empty.
+func LoadLoader(path string) (*LoaderConfig, error) {
+	var cfg LoaderConfig
+
+	if path == "" {
+		if err := cleanenv.ReadEnv(&cfg); err != nil {
+			return nil, fmt.Errorf("cannot read config: %w", err)
+		}
+
+		return &cfg, nil
+	}
+	if err := cleanenv.ReadConfig(path, &cfg); err != nil {
+		return nil, fmt.Errorf("cannot read config %s: %w", path, err)
+	}
+
+	return &cfg, nil
+}
+
+// ImportProfile returns the import section overridden by the profile, an empty name is the default profile.
+// The precedence is environment > profile > import section, flags of the loader override all of them.
+func (c *LoaderConfig) ImportProfile(name string) (ImportProfile, error) {
+	if name == "" {
+		name = c.Profile
+	}
+	if name == "" {
+		return c.Import, nil
+	}
+	profile, ok := c.Profiles[name]
+	if !ok {
+		return ImportProfile{}, fmt.Errorf("unknown profile %q", name)
+	}
+
+	// The environment is applied to the import section when the config is read, so it is applied again.
+	merged := c.Import.override(profile)
+	if err := cleanenv.ReadEnv(&merged); err != nil {
+		return ImportProfile{}, fmt.Errorf("cannot read config: %w", err)
+	}
+
+	return merged, nil
+}
+
+// override returns the settings with the values set in the profile.
+func (p ImportProfile) override(profile ImportProfile) ImportProfile {
+	overrideString(&p.Source, profile.Source)
+	overrideString(&p.Format, profile.Format)
+	overrideString(&p.Duplicates, profile.Duplicates)
+	overrideString(&p.Parallel, profile.Parallel)
+	overrideString(&p.Bogons, profile.Bogons)
+	overrideString(&p.Countries, profile.Countries)
+	overrideString(&p.Coordinates, profile.Coordinates)
+	overrideString(&p.MinQuality, profile.MinQuality)
+	if len(profile.Columns) > 0 {
+		p.Columns = profile.Columns
+	}
+	if profile.BatchSize != 0 {
+		p.BatchSize = profile.BatchSize
+	}
+
+	return p
+}
+
+func overrideString(value *string, override string) {
+	if override != "" {
+		*value = override
+	}
+}
@@ -0,0 +1,107 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoaderConfig_ImportProfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "loader.yaml")
+	content := `
+import:
+  source: "data_source"
+  batch_size: 100
+  countries: "flag"
+profiles:
+  acme:
+    source: "data_source/acme"
+    format: "tsv"
+    columns:
+      ip_address: "ip"
+    batch_size: 1000
+    min_quality: 50
+`
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	cfg, err := LoadLoader(path)
+	require.NoError(t, err)
+
+	base, err := cfg.ImportProfile("")
+	require.NoError(t, err)
+	assert.Equal(t, ImportProfile{
+		Source:      "data_source",
+		Format:      "csv",
+		Duplicates:  "last",
+		Parallel:    "0",
+		BatchSize:   100,
+		Bogons:      "accept",
+		Countries:   "flag",
+		Coordinates: "off",
+		MinQuality:  "0",
+	}, base)
+
+	acme, err := cfg.ImportProfile("acme")
+	require.NoError(t, err)
+	assert.Equal(t, ImportProfile{
+		Source:      "data_source/acme",
+		Format:      "tsv",
+		Columns:     map[string]string{"ip_address": "ip"},
+		Duplicates:  "last",
+		Parallel:    "0",
+		BatchSize:   1000,
+		Bogons:      "accept",
+		Countries:   "flag",
+		Coordinates: "off",
+		MinQuality:  "50",
+	}, acme)
+
+	// The default profile of the file is used without a name.
+	cfg.Profile = "acme"
+	got, err := cfg.ImportProfile("")
+	require.NoError(t, err)
+	assert.Equal(t, acme, got)
+
+	_, err = cfg.ImportProfile("unknown")
+	assert.EqualError(t, err, `unknown profile "unknown"`)
+}
+
+func TestLoaderConfig_ImportProfileEnvironment(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "loader.yaml")
+	content := `
+import:
+  batch_size: 100
+  countries: "flag"
+profiles:
+  acme:
+    format: "tsv"
+    parallel: "auto"
+    batch_size: 1000
+`
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	t.Setenv("LOADER_BATCH_SIZE", "500")
+	cfg, err := LoadLoader(path)
+	require.NoError(t, err)
+
+	// The environment overrides the profile, the profile overrides the import section.
+	got, err := cfg.ImportProfile("acme")
+	require.NoError(t, err)
+	assert.Equal(t, 500, got.BatchSize)
+	assert.Equal(t, "auto", got.Parallel)
+	assert.Equal(t, "tsv", got.Format)
+	assert.Equal(t, "flag", got.Countries)
+	assert.Equal(t, "accept", got.Bogons)
+}
+
+func TestLoadLoader_environment(t *testing.T) {
+	t.Setenv("LOADER_SOURCE", "feeds")
+	t.Setenv("LOADER_BATCH_SIZE", "500")
+
+	cfg, err := LoadLoader("")
+	require.NoError(t, err)
+	assert.Equal(t, "feeds", cfg.Import.Source)
+	assert.Equal(t, 500, cfg.Import.BatchSize)
+	assert.Equal(t, "csv", cfg.Import.Format)
+}
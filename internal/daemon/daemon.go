@@ -0,0 +1,379 @@
+package daemon
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/robfig/cron/v3"
+
+	"vio/internal/lib/logger/sl"
+	"vio/internal/models"
+	"vio/internal/processes"
+	"vio/internal/report"
+)
+
+// importFunc loads the file into the database.
+type importFunc func(ctx context.Context, db *sql.DB, path string, opts processes.ImportOptions, progress *processes.Progress) (*models.LoadStatistics, error)
+
+// Options of the loader daemon.
+type Options struct {
+	// Source is the directory new CSV files are dropped into.
+	Source string
+	// ProcessedDir receives imported files, empty means "processed" in the source directory.
+	ProcessedDir string
+	// FailedDir receives files failed to import, empty means "failed" in the source directory.
+	FailedDir string
+	// Schedule is a cron expression of directory polls, empty means the directory is watched for changes.
+	Schedule string
+	// Settle is the time a file must stay unchanged before it is imported.
+	Settle time.Duration
+	// Import are the options of the imports of files.
+	Import processes.ImportOptions
+	// Progress is the interval of progress log lines, 0 disables them.
+	Progress time.Duration
+}
+
+// Daemon imports CSV files as they appear in the source directory.
+type Daemon struct {
+	db       *sql.DB
+	log      *slog.Logger
+	opts     Options
+	schedule cron.Schedule
+	run      importFunc
+	now      func() time.Time
+
+	// files are CSV files waiting to settle.
+	files map[string]fileState
+	// handled are imported files that could not be moved out of the source directory,
+	// they are not imported again until they change.
+	handled map[string]fileState
+}
+
+type fileState struct {
+	size      int64
+	modTime   time.Time
+	changedAt time.Time
+}
+
+func New(db *sql.DB, log *slog.Logger, opts Options) (*Daemon, error) {
+	if opts.ProcessedDir == "" {
+		opts.ProcessedDir = filepath.Join(opts.Source, "processed")
+	}
+	if opts.FailedDir == "" {
+		opts.FailedDir = filepath.Join(opts.Source, "failed")
+	}
+
+	d := &Daemon{
+		db:      db,
+		log:     log,
+		opts:    opts,
+		run:     processes.Import,
+		now:     time.Now,
+		files:   make(map[string]fileState),
+		handled: make(map[string]fileState),
+	}
+
+	if opts.Schedule != "" {
+		schedule, err := cron.ParseStandard(opts.Schedule)
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule %q: %w", opts.Schedule, err)
+		}
+		d.schedule = schedule
+	}
+
+	for _, dir := range []string{opts.ProcessedDir, opts.FailedDir} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+	}
+
+	return d, nil
+}
+
+// Run imports files until the context is done. An import interrupted by the context
+// leaves its file in the source directory, so it is imported again on the next start.
+func (d *Daemon) Run(ctx context.Context) error {
+	var events chan fsnotify.Event
+	var watchErrors chan error
+	if d.schedule == nil {
+		watcher, err := fsnotify.NewWatcher()
+		if err != nil {
+			return err
+		}
+		defer watcher.Close()
+
+		if err := watcher.Add(d.opts.Source); err != nil {
+			return err
+		}
+		events, watchErrors = watcher.Events, watcher.Errors
+		d.log.Info("watching source directory", slog.String("source", d.opts.Source))
+	} else {
+		d.log.Info("polling source directory", slog.String("source", d.opts.Source), slog.String("schedule", d.opts.Schedule))
+	}
+
+	// Files present at the start are picked up without waiting for the schedule.
+	if err := d.scan(); err != nil {
+		return err
+	}
+
+	poll := d.nextPoll()
+	defer func() { poll.Stop() }()
+	check := time.NewTimer(time.Hour)
+	defer check.Stop()
+	d.armCheck(check)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event := <-events:
+			if !d.isSource(event.Name) {
+				continue
+			}
+		case err := <-watchErrors:
+			d.log.Error("failed to watch source directory", sl.Err(err))
+			continue
+		case <-poll.C:
+			poll = d.nextPoll()
+		case <-check.C:
+			if err := d.scan(); err != nil {
+				d.log.Error("failed to read source directory", sl.Err(err))
+			}
+			d.importSettled(ctx)
+		}
+
+		if err := d.scan(); err != nil {
+			d.log.Error("failed to read source directory", sl.Err(err))
+		}
+		d.armCheck(check)
+	}
+}
+
+// scan updates the states of CSV files in the source directory.
+func (d *Daemon) scan() error {
+	entries, err := os.ReadDir(d.opts.Source)
+	if err != nil {
+		return err
+	}
+
+	now := d.now()
+	seen := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || !d.isSource(entry.Name()) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			// The file has been removed since the directory was read.
+			continue
+		}
+
+		path := filepath.Join(d.opts.Source, entry.Name())
+		seen[path] = true
+
+		if state, ok := d.handled[path]; ok {
+			if state.size == info.Size() && state.modTime.Equal(info.ModTime()) {
+				continue
+			}
+			delete(d.handled, path)
+		}
+
+		state, ok := d.files[path]
+		if !ok || state.size != info.Size() || !state.modTime.Equal(info.ModTime()) {
+			d.files[path] = fileState{
+				size:      info.Size(),
+				modTime:   info.ModTime(),
+				changedAt: now,
+			}
+		}
+	}
+
+	for path := range d.files {
+		if !seen[path] {
+			delete(d.files, path)
+		}
+	}
+	for path := range d.handled {
+		if !seen[path] {
+			delete(d.handled, path)
+		}
+	}
+
+	return nil
+}
+
+// importSettled imports files unchanged for the settle time in the order of their names.
+func (d *Daemon) importSettled(ctx context.Context) {
+	for _, path := range sortedKeys(d.files) {
+		if ctx.Err() != nil {
+			return
+		}
+		if d.now().Sub(d.files[path].changedAt) < d.opts.Settle {
+			continue
+		}
+
+		if !d.importFile(ctx, path) && ctx.Err() == nil {
+			d.handled[path] = d.files[path]
+		}
+		delete(d.files, path)
+	}
+}
+
+// importFile imports the file and moves it to the processed or failed directory,
+// it returns false when the file is left in the source directory.
+func (d *Daemon) importFile(ctx context.Context, path string) bool {
+	log := d.log.With(slog.String("file", filepath.Base(path)))
+	log.Info("import started")
+
+	progress := &processes.Progress{}
+	reportCtx, stopReport := context.WithCancel(ctx)
+	reported := make(chan struct{})
+	go func() {
+		report.NewLog(log, progress.Snapshot, d.opts.Progress).Run(reportCtx)
+		close(reported)
+	}()
+
+	statistics, err := d.run(ctx, d.db, path, d.opts.Import, progress)
+	stopReport()
+	<-reported
+	if ctx.Err() != nil {
+		var written int64
+		if statistics != nil {
+			written = statistics.Written
+		}
+		log.Warn("import aborted, the file is left in the source directory", slog.Int64("written", written))
+		return false
+	}
+
+	targetDir := d.opts.ProcessedDir
+	if err != nil {
+		log.Error("import failed", sl.Err(err))
+		targetDir = d.opts.FailedDir
+	} else {
+		log.Info("import finished",
+			slog.String("load_time", statistics.LoadTime),
+			slog.Int64("accepted", statistics.Accepted),
+			slog.Int64("discarded", statistics.Discarded),
+			slog.Int64("total", statistics.Total),
+			slog.Int64("written", statistics.Written),
+		)
+	}
+
+	if err := moveFile(path, targetDir, d.now()); err != nil {
+		log.Error("failed to move file, it is not imported again until it changes", sl.Err(err))
+		return false
+	}
+
+	return true
+}
+
+// armCheck sets the check timer to the time the earliest waiting file settles,
+// the timer is stopped when no files are waiting.
+func (d *Daemon) armCheck(timer *time.Timer) {
+	if !timer.Stop() {
+		select {
+		case <-timer.C:
+		default:
+		}
+	}
+	if len(d.files) == 0 {
+		return
+	}
+
+	now := d.now()
+	next := time.Duration(-1)
+	for _, state := range d.files {
+		if wait := state.changedAt.Add(d.opts.Settle).Sub(now); next < 0 || wait < next {
+			next = wait
+		}
+	}
+	if next < 0 {
+		next = 0
+	}
+	timer.Reset(next)
+}
+
+// nextPoll returns the timer of the next scheduled poll, it never fires in the watch mode.
+func (d *Daemon) nextPoll() *time.Timer {
+	if d.schedule == nil {
+		timer := time.NewTimer(time.Hour)
+		timer.Stop()
+		return timer
+	}
+	now := d.now()
+
+	return time.NewTimer(d.schedule.Next(now).Sub(now))
+}
+
+// moveFile moves the file to the directory, a timestamp is added to the name if the target exists.
+// The file is copied and removed when the directory is on another file system.
+func moveFile(path, dir string, now time.Time) error {
+	name := filepath.Base(path)
+	target := filepath.Join(dir, name)
+	if _, err := os.Stat(target); err == nil {
+		ext := filepath.Ext(name)
+		target = filepath.Join(dir, strings.TrimSuffix(name, ext)+"-"+now.UTC().Format("20060102T150405")+ext)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	err := os.Rename(path, target)
+	if errors.Is(err, syscall.EXDEV) {
+		return copyFile(path, target)
+	}
+
+	return err
+}
+
+// copyFile copies the file to the target and removes it, the partial copy is removed on error.
+func copyFile(path, target string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(dst, src)
+	if err == nil {
+		err = dst.Sync()
+	}
+	if errClose := dst.Close(); err == nil {
+		err = errClose
+	}
+	if err != nil {
+		_ = os.Remove(target)
+		return err
+	}
+
+	return os.Remove(path)
+}
+
+// isSource reports whether the file has the extension of the source format.
+func (d *Daemon) isSource(name string) bool {
+	return filepath.Ext(name) == d.opts.Import.Format.Extension()
+}
+
+func sortedKeys(files map[string]fileState) []string {
+	keys := make([]string, 0, len(files))
+	for key := range files {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,198 @@
+package daemon
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vio/internal/models"
+	"vio/internal/processes"
+)
+
+func TestDaemon_Run(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule string
+	}{
+		{
+			name: "Watch",
+		},
+		{
+			name:     "Schedule",
+			schedule: "* * * * *",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			source := t.TempDir()
+			d, err := New(nil, slog.New(slog.NewTextHandler(io.Discard, nil)), Options{
+				Source:   source,
+				Schedule: tt.schedule,
+				Settle:   50 * time.Millisecond,
+			})
+			require.NoError(t, err)
+
+			var mu sync.Mutex
+			var imported []string
+			d.run = func(_ context.Context, _ *sql.DB, path string, _ processes.ImportOptions, _ *processes.Progress) (*models.LoadStatistics, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				imported = append(imported, filepath.Base(path))
+				if filepath.Base(path) == "bad.csv" {
+					return nil, errors.New("record on line 2: wrong number of fields")
+				}
+				return &models.LoadStatistics{}, nil
+			}
+
+			// Files present at the start are imported in both modes.
+			require.NoError(t, os.WriteFile(filepath.Join(source, "good.csv"), []byte("data"), 0o600))
+			require.NoError(t, os.WriteFile(filepath.Join(source, "bad.csv"), []byte("data"), 0o600))
+			require.NoError(t, os.WriteFile(filepath.Join(source, "notes.txt"), []byte("data"), 0o600))
+
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan error)
+			go func() {
+				done <- d.Run(ctx)
+			}()
+
+			assert.Eventually(t, func() bool {
+				_, errGood := os.Stat(filepath.Join(source, "processed", "good.csv"))
+				_, errBad := os.Stat(filepath.Join(source, "failed", "bad.csv"))
+				return errGood == nil && errBad == nil
+			}, 5*time.Second, 10*time.Millisecond)
+
+			cancel()
+			require.NoError(t, <-done)
+
+			mu.Lock()
+			defer mu.Unlock()
+			assert.Equal(t, []string{"bad.csv", "good.csv"}, imported)
+			assert.FileExists(t, filepath.Join(source, "notes.txt"))
+		})
+	}
+}
+
+func TestDaemon_RunWatchNewFile(t *testing.T) {
+	source := t.TempDir()
+	d, err := New(nil, slog.New(slog.NewTextHandler(io.Discard, nil)), Options{
+		Source: source,
+		Settle: 100 * time.Millisecond,
+	})
+	require.NoError(t, err)
+
+	imported := make(chan string, 1)
+	d.run = func(_ context.Context, _ *sql.DB, path string, _ processes.ImportOptions, _ *processes.Progress) (*models.LoadStatistics, error) {
+		data, err := os.ReadFile(path)
+		imported <- string(data)
+		return &models.LoadStatistics{}, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		_ = d.Run(ctx)
+	}()
+
+	// The file is imported only after it stops changing.
+	path := filepath.Join(source, "new.csv")
+	file, err := os.Create(path)
+	require.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		_, err = file.WriteString("line\n")
+		require.NoError(t, err)
+		time.Sleep(50 * time.Millisecond)
+	}
+	require.NoError(t, file.Close())
+
+	select {
+	case data := <-imported:
+		assert.Equal(t, "line\nline\nline\n", data)
+	case <-time.After(5 * time.Second):
+		t.Fatal("file is not imported")
+	}
+}
+
+func TestDaemon_RunMoveFailed(t *testing.T) {
+	source := t.TempDir()
+	d, err := New(nil, slog.New(slog.NewTextHandler(io.Discard, nil)), Options{
+		Source: source,
+		Settle: 20 * time.Millisecond,
+	})
+	require.NoError(t, err)
+
+	// The processed directory is replaced by a file, so moving fails even for root.
+	require.NoError(t, os.Remove(filepath.Join(source, "processed")))
+	require.NoError(t, os.WriteFile(filepath.Join(source, "processed"), nil, 0o400))
+
+	imported := make(chan string, 10)
+	d.run = func(_ context.Context, _ *sql.DB, path string, _ processes.ImportOptions, _ *processes.Progress) (*models.LoadStatistics, error) {
+		data, err := os.ReadFile(path)
+		imported <- filepath.Base(path) + ":" + string(data)
+		return &models.LoadStatistics{}, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		_ = d.Run(ctx)
+	}()
+
+	next := func() string {
+		select {
+		case name := <-imported:
+			return name
+		case <-time.After(5 * time.Second):
+			t.Fatal("file is not imported")
+			return ""
+		}
+	}
+
+	path := filepath.Join(source, "data.csv")
+	require.NoError(t, os.WriteFile(path, []byte("first"), 0o600))
+	assert.Equal(t, "data.csv:first", next())
+
+	// Another file makes the daemon scan the directory again, the unchanged file is skipped.
+	require.NoError(t, os.WriteFile(filepath.Join(source, "other.csv"), []byte("other"), 0o600))
+	assert.Equal(t, "other.csv:other", next())
+
+	// The file is imported again once it changes.
+	require.NoError(t, os.WriteFile(path, []byte("second"), 0o600))
+	assert.Equal(t, "data.csv:second", next())
+	assert.FileExists(t, path)
+}
+
+func TestNew_InvalidSchedule(t *testing.T) {
+	_, err := New(nil, slog.New(slog.NewTextHandler(io.Discard, nil)), Options{
+		Source:   t.TempDir(),
+		Schedule: "every minute",
+	})
+	assert.Error(t, err)
+}
+
+func Test_moveFile(t *testing.T) {
+	source := t.TempDir()
+	target := t.TempDir()
+	now := time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 2; i++ {
+		require.NoError(t, os.WriteFile(filepath.Join(source, "data.csv"), []byte("data"), 0o600))
+		require.NoError(t, moveFile(filepath.Join(source, "data.csv"), target, now))
+	}
+
+	assert.FileExists(t, filepath.Join(target, "data.csv"))
+	assert.FileExists(t, filepath.Join(target, "data-20240102T100000.csv"))
+	assert.NoFileExists(t, filepath.Join(source, "data.csv"))
+}
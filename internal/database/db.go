@@ -2,13 +2,47 @@ package database
 
 import (
 	"database/sql"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 )
 
-func GetDB(path string) (*sql.DB, error) {
+// Options of the connection pool, zero values keep the defaults of database/sql and the server.
+type Options struct {
+	// MaxOpenConns limits the number of open connections.
+	MaxOpenConns int
+	// MaxIdleConns is the number of idle connections kept in the pool.
+	MaxIdleConns int
+	// ConnMaxLifetime closes connections older than it when they are idle.
+	ConnMaxLifetime time.Duration
+	// StatementTimeout aborts statements running longer on the server (statement_timeout of the sessions).
+	StatementTimeout time.Duration
+}
+
+func GetDB(path string, opts Options) (*sql.DB, error) {
+	if opts.StatementTimeout > 0 {
+		var err error
+		path, err = withStatementTimeout(path, opts.StatementTimeout)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	db, err := sql.Open("postgres", path)
 	if err != nil {
 		return nil, err
 	}
+	if opts.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(opts.MaxOpenConns)
+	}
+	if opts.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(opts.MaxIdleConns)
+	}
+	if opts.ConnMaxLifetime > 0 {
+		db.SetConnMaxLifetime(opts.ConnMaxLifetime)
+	}
 
 	err = db.Ping()
 	if err != nil {
@@ -17,3 +51,22 @@ func GetDB(path string) (*sql.DB, error) {
 
 	return db, nil
 }
+
+// withStatementTimeout adds the statement_timeout run-time parameter in milliseconds to the connection string,
+// the driver sends unknown parameters to the server. Both URL and key/value connection strings are supported.
+func withStatementTimeout(path string, timeout time.Duration) (string, error) {
+	milliseconds := strconv.FormatInt(timeout.Milliseconds(), 10)
+	if !strings.HasPrefix(path, "postgres://") && !strings.HasPrefix(path, "postgresql://") {
+		return strings.TrimSpace(path + " statement_timeout=" + milliseconds), nil
+	}
+
+	u, err := url.Parse(path)
+	if err != nil {
+		return "", fmt.Errorf("invalid connection string: %w", err)
+	}
+	query := u.Query()
+	query.Set("statement_timeout", milliseconds)
+	u.RawQuery = query.Encode()
+
+	return u.String(), nil
+}
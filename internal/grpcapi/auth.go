@@ -0,0 +1,184 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"vio/internal/auth"
+	"vio/internal/grpcapi/geolocationpb"
+	"vio/internal/lib/logger/sl"
+)
+
+// methodScopes are the scopes required by the geolocation methods,
+// other services (health checking, reflection) do not require API keys.
+var methodScopes = map[string]auth.Scope{
+	geolocationpb.Geolocation_Lookup_FullMethodName:      auth.ScopeLookup,
+	geolocationpb.Geolocation_BatchLookup_FullMethodName: auth.ScopeBatch,
+	geolocationpb.Geolocation_Stats_FullMethodName:       auth.ScopeBatch,
+}
+
+// Authenticator checks API keys of calls the same way as the REST API does.
+type Authenticator struct {
+	store   *auth.Store
+	limiter *RateLimiter
+	log     *slog.Logger
+}
+
+// NewAuthenticator creates the authenticator, a nil limiter disables the rate limit per API key.
+func NewAuthenticator(store *auth.Store, limiter *RateLimiter, log *slog.Logger) *Authenticator {
+	return &Authenticator{
+		store:   store,
+		limiter: limiter,
+		log:     log,
+	}
+}
+
+// Unary returns an interceptor authenticating unary calls, a call counts as one request.
+func (a *Authenticator) Unary() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		key, err := a.authenticate(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		if key == nil {
+			return handler(ctx, req)
+		}
+		if err := a.allow(ctx, key); err != nil {
+			return nil, err
+		}
+
+		return handler(auth.WithAPIKey(ctx, key), req)
+	}
+}
+
+// Stream returns an interceptor authenticating streaming calls,
+// every received message counts as one request, so a stream can not bypass the rate limit and the daily quota.
+func (a *Authenticator) Stream() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		key, err := a.authenticate(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		if key == nil {
+			return handler(srv, ss)
+		}
+
+		return handler(srv, &authenticatedStream{
+			ServerStream:  ss,
+			ctx:           auth.WithAPIKey(ss.Context(), key),
+			authenticator: a,
+			key:           key,
+		})
+	}
+}
+
+// authenticate checks the key from the x-api-key or authorization metadata,
+// the key is nil for methods that do not require API keys.
+func (a *Authenticator) authenticate(ctx context.Context, method string) (*auth.APIKey, error) {
+	scope, ok := methodScopes[method]
+	if !ok {
+		return nil, nil
+	}
+
+	secret := apiKeyFromMetadata(ctx)
+	if secret == "" {
+		return nil, status.Error(codes.Unauthenticated, "API key is required")
+	}
+
+	key, err := a.store.Authenticate(ctx, secret)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidKey) {
+			return nil, status.Error(codes.Unauthenticated, "invalid API key")
+		}
+		a.log.Error("failed to authenticate API key", sl.Err(err))
+		return nil, status.Error(codes.Internal, "failed to authenticate")
+	}
+
+	if !key.HasScope(scope) {
+		return nil, status.Error(codes.PermissionDenied, "API key is not allowed to access this method")
+	}
+
+	return key, nil
+}
+
+// allow checks the rate limit of the key and then consumes its quota,
+// so calls rejected by the rate limit are not counted in the usage.
+func (a *Authenticator) allow(ctx context.Context, key *auth.APIKey) error {
+	if a.limiter != nil {
+		if err := a.limiter.allowKey(ctx, key); err != nil {
+			return err
+		}
+	}
+
+	return a.consumeQuota(ctx, key)
+}
+
+// consumeQuota counts a request of the key and rejects it when the daily quota is exceeded.
+func (a *Authenticator) consumeQuota(ctx context.Context, key *auth.APIKey) error {
+	requests, err := a.store.ConsumeQuota(ctx, key.ID)
+	if err != nil {
+		a.log.Error("failed to count API key usage", sl.Err(err), slog.Int64("key_id", key.ID))
+		return status.Error(codes.Internal, "failed to authenticate")
+	}
+	if key.DailyQuota > 0 {
+		remaining := key.DailyQuota - requests
+		if remaining < 0 {
+			remaining = 0
+		}
+		// Headers can not be changed after the first message of a stream, the error is ignored.
+		_ = grpc.SetHeader(ctx, metadata.Pairs(
+			"x-quota-limit", strconv.FormatInt(key.DailyQuota, 10),
+			"x-quota-remaining", strconv.FormatInt(remaining, 10),
+		))
+		if requests > key.DailyQuota {
+			return status.Error(codes.ResourceExhausted, "daily quota exceeded")
+		}
+	}
+
+	return nil
+}
+
+func apiKeyFromMetadata(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get("x-api-key"); len(values) > 0 && values[0] != "" {
+		return strings.TrimSpace(values[0])
+	}
+
+	if values := md.Get("authorization"); len(values) > 0 {
+		scheme, token, found := strings.Cut(values[0], " ")
+		if found && strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(token)
+		}
+	}
+
+	return ""
+}
+
+// authenticatedStream passes the context with the authenticated key to the stream handler
+// and counts every received message against the rate limit and the quota of the key.
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx           context.Context
+	authenticator *Authenticator
+	key           *auth.APIKey
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *authenticatedStream) RecvMsg(m any) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+
+	return s.authenticator.allow(s.ctx, s.key)
+}
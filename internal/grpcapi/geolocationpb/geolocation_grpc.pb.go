@@ -0,0 +1,224 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: geolocation/v1/geolocation.proto
+
+package geolocationpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	Geolocation_Lookup_FullMethodName      = "/geolocation.v1.Geolocation/Lookup"
+	Geolocation_BatchLookup_FullMethodName = "/geolocation.v1.Geolocation/BatchLookup"
+	Geolocation_Stats_FullMethodName       = "/geolocation.v1.Geolocation/Stats"
+)
+
+// GeolocationClient is the client API for Geolocation service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type GeolocationClient interface {
+	// Lookup returns the location of the IP address.
+	// Reserved addresses of the bogon policy fail with FAILED_PRECONDITION.
+	Lookup(ctx context.Context, in *LookupRequest, opts ...grpc.CallOption) (*Location, error)
+	// BatchLookup returns locations of the streamed IP addresses in the order of requests.
+	BatchLookup(ctx context.Context, opts ...grpc.CallOption) (Geolocation_BatchLookupClient, error)
+	// Stats returns statistics of IP addresses per country or city.
+	Stats(ctx context.Context, in *StatsRequest, opts ...grpc.CallOption) (*StatsResponse, error)
+}
+
+type geolocationClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewGeolocationClient(cc grpc.ClientConnInterface) GeolocationClient {
+	return &geolocationClient{cc}
+}
+
+func (c *geolocationClient) Lookup(ctx context.Context, in *LookupRequest, opts ...grpc.CallOption) (*Location, error) {
+	out := new(Location)
+	err := c.cc.Invoke(ctx, Geolocation_Lookup_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *geolocationClient) BatchLookup(ctx context.Context, opts ...grpc.CallOption) (Geolocation_BatchLookupClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Geolocation_ServiceDesc.Streams[0], Geolocation_BatchLookup_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &geolocationBatchLookupClient{stream}
+	return x, nil
+}
+
+type Geolocation_BatchLookupClient interface {
+	Send(*LookupRequest) error
+	Recv() (*LookupResponse, error)
+	grpc.ClientStream
+}
+
+type geolocationBatchLookupClient struct {
+	grpc.ClientStream
+}
+
+func (x *geolocationBatchLookupClient) Send(m *LookupRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *geolocationBatchLookupClient) Recv() (*LookupResponse, error) {
+	m := new(LookupResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *geolocationClient) Stats(ctx context.Context, in *StatsRequest, opts ...grpc.CallOption) (*StatsResponse, error) {
+	out := new(StatsResponse)
+	err := c.cc.Invoke(ctx, Geolocation_Stats_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GeolocationServer is the server API for Geolocation service.
+// All implementations must embed UnimplementedGeolocationServer
+// for forward compatibility
+type GeolocationServer interface {
+	// Lookup returns the location of the IP address.
+	// Reserved addresses of the bogon policy fail with FAILED_PRECONDITION.
+	Lookup(context.Context, *LookupRequest) (*Location, error)
+	// BatchLookup returns locations of the streamed IP addresses in the order of requests.
+	BatchLookup(Geolocation_BatchLookupServer) error
+	// Stats returns statistics of IP addresses per country or city.
+	Stats(context.Context, *StatsRequest) (*StatsResponse, error)
+	mustEmbedUnimplementedGeolocationServer()
+}
+
+// UnimplementedGeolocationServer must be embedded to have forward compatible implementations.
+type UnimplementedGeolocationServer struct {
+}
+
+func (UnimplementedGeolocationServer) Lookup(context.Context, *LookupRequest) (*Location, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Lookup not implemented")
+}
+func (UnimplementedGeolocationServer) BatchLookup(Geolocation_BatchLookupServer) error {
+	return status.Errorf(codes.Unimplemented, "method BatchLookup not implemented")
+}
+func (UnimplementedGeolocationServer) Stats(context.Context, *StatsRequest) (*StatsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Stats not implemented")
+}
+func (UnimplementedGeolocationServer) mustEmbedUnimplementedGeolocationServer() {}
+
+// UnsafeGeolocationServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to GeolocationServer will
+// result in compilation errors.
+type UnsafeGeolocationServer interface {
+	mustEmbedUnimplementedGeolocationServer()
+}
+
+func RegisterGeolocationServer(s grpc.ServiceRegistrar, srv GeolocationServer) {
+	s.RegisterService(&Geolocation_ServiceDesc, srv)
+}
+
+func _Geolocation_Lookup_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LookupRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GeolocationServer).Lookup(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Geolocation_Lookup_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GeolocationServer).Lookup(ctx, req.(*LookupRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Geolocation_BatchLookup_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(GeolocationServer).BatchLookup(&geolocationBatchLookupServer{stream})
+}
+
+type Geolocation_BatchLookupServer interface {
+	Send(*LookupResponse) error
+	Recv() (*LookupRequest, error)
+	grpc.ServerStream
+}
+
+type geolocationBatchLookupServer struct {
+	grpc.ServerStream
+}
+
+func (x *geolocationBatchLookupServer) Send(m *LookupResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *geolocationBatchLookupServer) Recv() (*LookupRequest, error) {
+	m := new(LookupRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _Geolocation_Stats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GeolocationServer).Stats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Geolocation_Stats_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GeolocationServer).Stats(ctx, req.(*StatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Geolocation_ServiceDesc is the grpc.ServiceDesc for Geolocation service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Geolocation_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "geolocation.v1.Geolocation",
+	HandlerType: (*GeolocationServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Lookup",
+			Handler:    _Geolocation_Lookup_Handler,
+		},
+		{
+			MethodName: "Stats",
+			Handler:    _Geolocation_Stats_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "BatchLookup",
+			Handler:       _Geolocation_BatchLookup_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "geolocation/v1/geolocation.proto",
+}
@@ -0,0 +1,110 @@
+package grpcapi
+
+import (
+	"context"
+	"net"
+	"strconv"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"vio/internal/auth"
+	"vio/internal/lib/ratelimit"
+)
+
+// RateLimiter limits calls per client IP address and per API key.
+// The limiters are shared with the REST API, so both APIs take tokens from the same buckets.
+type RateLimiter struct {
+	byIP  *ratelimit.Limiter
+	byKey *ratelimit.Limiter
+}
+
+func NewRateLimiter(byIP, byKey *ratelimit.Limiter) *RateLimiter {
+	return &RateLimiter{
+		byIP:  byIP,
+		byKey: byKey,
+	}
+}
+
+// UnaryByIP returns an interceptor limiting unary calls per peer IP address.
+// It must run before authentication, so invalid keys do not reach the database unlimited.
+func (l *RateLimiter) UnaryByIP() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := l.allowIP(ctx); err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamByIP returns an interceptor limiting streaming calls per peer IP address,
+// the stream and every received message take a token.
+func (l *RateLimiter) StreamByIP() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := l.allowIP(ss.Context()); err != nil {
+			return err
+		}
+
+		return handler(srv, &limitedStream{ServerStream: ss, allow: l.allowIP})
+	}
+}
+
+func (l *RateLimiter) allowIP(ctx context.Context) error {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return status.Error(codes.InvalidArgument, "client IP address can not be determined")
+	}
+	tcpAddr, ok := p.Addr.(*net.TCPAddr)
+	if !ok {
+		return status.Error(codes.InvalidArgument, "client IP address can not be determined")
+	}
+	addr := tcpAddr.AddrPort().Addr().Unmap().WithZone("")
+
+	return allow(ctx, l.byIP, "ip:"+addr.String())
+}
+
+// allowKey limits calls per API key, the authenticator checks it before the daily quota is consumed,
+// so rejected calls are not counted in the usage of the key.
+func (l *RateLimiter) allowKey(ctx context.Context, key *auth.APIKey) error {
+	return allow(ctx, l.byKey, "key:"+strconv.FormatInt(key.ID, 10))
+}
+
+// allow sets the rate limit headers and returns ResourceExhausted when the call is not allowed.
+// Headers can not be changed after the first message of a stream, the errors are ignored.
+func allow(ctx context.Context, limiter *ratelimit.Limiter, key string) error {
+	result := limiter.Allow(key)
+
+	md := metadata.Pairs(
+		"x-ratelimit-limit", strconv.Itoa(result.Limit),
+		"x-ratelimit-remaining", strconv.Itoa(result.Remaining),
+		"x-ratelimit-reset", strconv.Itoa(ratelimit.CeilSeconds(result.Reset)),
+	)
+	if !result.Allowed {
+		md.Set("retry-after", strconv.Itoa(ratelimit.CeilSeconds(result.RetryAfter)))
+	}
+	_ = grpc.SetHeader(ctx, md)
+
+	if !result.Allowed {
+		return status.Error(codes.ResourceExhausted, "too many requests")
+	}
+
+	return nil
+}
+
+// limitedStream takes a token for every received message.
+type limitedStream struct {
+	grpc.ServerStream
+	allow func(ctx context.Context) error
+}
+
+func (s *limitedStream) RecvMsg(m any) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+
+	return s.allow(s.Context())
+}
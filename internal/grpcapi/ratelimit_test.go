@@ -0,0 +1,87 @@
+package grpcapi
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"vio/internal/grpcapi/geolocationpb"
+	"vio/internal/lib/ratelimit"
+)
+
+// startTCPServer serves on a loopback TCP port, so the peer has an IP address to limit.
+func startTCPServer(t *testing.T, opts Options) geolocationpb.GeolocationClient {
+	t.Helper()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	for i := 0; i < 10; i++ {
+		mock.ExpectQuery("SELECT (.+) FROM location WHERE ip_address = (.+)").
+			WillReturnRows(sqlmock.NewRows(locationColumns))
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	srv := NewServer(db, nil, slog.New(slog.NewTextHandler(io.Discard, nil)), opts)
+	go func() {
+		_ = srv.Serve(listener)
+	}()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.Dial(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return geolocationpb.NewGeolocationClient(conn)
+}
+
+func TestRateLimiter_Unary(t *testing.T) {
+	t.Parallel()
+
+	byIP := ratelimit.New(0, 1)
+	client := startTCPServer(t, Options{RateLimiter: NewRateLimiter(byIP, ratelimit.New(0, 1))})
+
+	var header metadata.MD
+	_, err := client.Lookup(context.Background(), &geolocationpb.LookupRequest{IpAddress: "70.95.73.73"}, grpc.Header(&header))
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	assert.Equal(t, []string{"1"}, header.Get("x-ratelimit-limit"))
+	assert.Equal(t, []string{"0"}, header.Get("x-ratelimit-remaining"))
+
+	_, err = client.Lookup(context.Background(), &geolocationpb.LookupRequest{IpAddress: "70.95.73.73"}, grpc.Header(&header))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.NotEmpty(t, header.Get("retry-after"))
+
+	// The bucket is shared with the REST API.
+	assert.False(t, byIP.Allow("ip:127.0.0.1").Allowed)
+}
+
+func TestRateLimiter_Stream(t *testing.T) {
+	t.Parallel()
+
+	// The stream takes one token and the first IP address the second one.
+	client := startTCPServer(t, Options{RateLimiter: NewRateLimiter(ratelimit.New(0, 2), ratelimit.New(0, 1))})
+
+	stream, err := client.BatchLookup(context.Background())
+	require.NoError(t, err)
+
+	require.NoError(t, stream.Send(&geolocationpb.LookupRequest{IpAddress: "70.95.73.73"}))
+	resp, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, "location not found", resp.GetError())
+
+	require.NoError(t, stream.Send(&geolocationpb.LookupRequest{IpAddress: "70.95.73.73"}))
+	_, err = stream.Recv()
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
@@ -0,0 +1,276 @@
+package grpcapi
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"log/slog"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+
+	"vio/internal/auth"
+	"vio/internal/grpcapi/geolocationpb"
+	"vio/internal/lib/logger/sl"
+	"vio/internal/models"
+	"vio/internal/processes"
+	"vio/internal/shape"
+)
+
+// Service implements the Geolocation gRPC service on top of the same data access as the REST API.
+type Service struct {
+	geolocationpb.UnimplementedGeolocationServer
+
+	db   *sql.DB
+	log  *slog.Logger
+	opts Options
+}
+
+// Options of the gRPC service shared with the REST API, the zero value exposes all fields.
+type Options struct {
+	// Shaper hides the location fields outside of the response_fields allow-list.
+	Shaper *shape.Shaper
+	// RateLimiter limits calls per peer IP address and per API key, nil disables rate limiting.
+	RateLimiter *RateLimiter
+	// QueryTimeout cancels the queries of a lookup or statistics running longer, 0 disables it.
+	QueryTimeout time.Duration
+	// Bogons is the handling of special-purpose addresses, BogonsAccept by default.
+	Bogons processes.BogonPolicy
+}
+
+func NewService(db *sql.DB, log *slog.Logger, opts Options) *Service {
+	return &Service{
+		db:   db,
+		log:  log,
+		opts: opts,
+	}
+}
+
+// NewServer creates a gRPC server with the geolocation service, health checking and reflection.
+// API keys are required when the store is not nil.
+func NewServer(db *sql.DB, store *auth.Store, log *slog.Logger, opts Options, serverOpts ...grpc.ServerOption) *grpc.Server {
+	var (
+		unary  []grpc.UnaryServerInterceptor
+		stream []grpc.StreamServerInterceptor
+	)
+	// Limit by IP runs before authentication, the authenticator limits by key before consuming the quota,
+	// like in the REST API.
+	if opts.RateLimiter != nil {
+		unary = append(unary, opts.RateLimiter.UnaryByIP())
+		stream = append(stream, opts.RateLimiter.StreamByIP())
+	}
+	if store != nil {
+		authenticator := NewAuthenticator(store, opts.RateLimiter, log)
+		unary = append(unary, authenticator.Unary())
+		stream = append(stream, authenticator.Stream())
+	}
+	serverOpts = append(serverOpts,
+		grpc.ChainUnaryInterceptor(unary...),
+		grpc.ChainStreamInterceptor(stream...),
+	)
+
+	srv := grpc.NewServer(serverOpts...)
+	geolocationpb.RegisterGeolocationServer(srv, NewService(db, log, opts))
+
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+	healthServer.SetServingStatus(geolocationpb.Geolocation_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(srv, healthServer)
+
+	reflection.Register(srv)
+
+	return srv
+}
+
+// Lookup returns the location of the IP address.
+func (s *Service) Lookup(ctx context.Context, req *geolocationpb.LookupRequest) (*geolocationpb.Location, error) {
+	location, err := s.lookup(ctx, req.GetIpAddress())
+	if err != nil {
+		return nil, err
+	}
+
+	return s.toLocation(location), nil
+}
+
+// BatchLookup returns locations of the streamed IP addresses in the order of requests.
+// Invalid and unknown addresses are reported in the response and do not end the stream.
+func (s *Service) BatchLookup(stream geolocationpb.Geolocation_BatchLookupServer) error {
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		resp := &geolocationpb.LookupResponse{IpAddress: req.GetIpAddress()}
+		location, err := s.lookup(stream.Context(), req.GetIpAddress())
+		switch status.Code(err) {
+		case codes.OK:
+			resp.Location = s.toLocation(location)
+		case codes.InvalidArgument, codes.NotFound, codes.FailedPrecondition, codes.DeadlineExceeded:
+			resp.Error = status.Convert(err).Message()
+		default:
+			return err
+		}
+
+		if err := stream.Send(resp); err != nil {
+			return err
+		}
+	}
+}
+
+// Stats returns statistics of IP addresses per country or city.
+func (s *Service) Stats(ctx context.Context, req *geolocationpb.StatsRequest) (*geolocationpb.StatsResponse, error) {
+	if req.GetTop() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "invalid top: must not be negative")
+	}
+
+	fn := processes.GetCountryStatistics
+	switch req.GetGroup() {
+	case geolocationpb.StatsRequest_GROUP_UNSPECIFIED, geolocationpb.StatsRequest_GROUP_COUNTRY:
+	case geolocationpb.StatsRequest_GROUP_CITY:
+		fn = processes.GetCityStatistics
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "invalid group: %v", req.GetGroup())
+	}
+
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	statistics, err := fn(ctx, s.db, int(req.GetTop()))
+	if err != nil {
+		return nil, s.queryError(ctx, err, "failed to retrieve statistics")
+	}
+
+	resp := &geolocationpb.StatsResponse{Items: make([]*geolocationpb.LocationStatistics, 0, len(statistics))}
+	for _, item := range statistics {
+		s.opts.Shaper.HideStatistics(&item)
+		resp.Items = append(resp.Items, toLocationStatistics(item))
+	}
+
+	return resp, nil
+}
+
+// lookup validates the IP address and reads its location like the REST API does,
+// reserved addresses of the bogon policy fail with FailedPrecondition instead of 422.
+func (s *Service) lookup(ctx context.Context, ipAddress string) (*models.Location, error) {
+	addr, ok := processes.ParseIPAddress(ipAddress)
+	if !ok {
+		return nil, status.Error(codes.InvalidArgument, "invalid IP address")
+	}
+	// Locations are stored by the canonical form of the address.
+	ipAddress = addr.String()
+	class, reserved := s.opts.Bogons.Reserved(addr)
+	if reserved && s.opts.Bogons == processes.BogonsReject {
+		// Rejected addresses are never imported.
+		return nil, reservedAddressError(class)
+	}
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	location, err := processes.GetLocation(ctx, s.db, ipAddress)
+	if err != nil {
+		return nil, s.queryError(ctx, err, "failed to retrieve location")
+	}
+	if len(location.IPAddress) == 0 {
+		if reserved {
+			return nil, reservedAddressError(class)
+		}
+		return nil, status.Error(codes.NotFound, "location not found")
+	}
+	if reserved {
+		location.AddressClass = string(class)
+	}
+
+	return location, nil
+}
+
+// reservedAddressError reports a special-purpose address which has no public location.
+func reservedAddressError(class processes.AddressClass) error {
+	return status.Errorf(codes.FailedPrecondition, "reserved address: %s", class)
+}
+
+// queryContext limits the queries with the query timeout, like the REST API does.
+func (s *Service) queryContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.opts.QueryTimeout <= 0 {
+		return ctx, func() {}
+	}
+
+	return context.WithTimeout(ctx, s.opts.QueryTimeout)
+}
+
+// queryError returns DeadlineExceeded for queries cancelled by the timeout, other errors are logged as internal.
+func (s *Service) queryError(ctx context.Context, err error, message string) error {
+	// The driver may return the error of the cancelled statement instead of the context error.
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return status.Error(codes.DeadlineExceeded, "query timeout exceeded")
+	}
+	s.log.Error(message, sl.Err(err))
+
+	return status.Error(codes.Internal, message)
+}
+
+// toLocation converts the location without the fields hidden by the allow-list.
+func (s *Service) toLocation(location *models.Location) *geolocationpb.Location {
+	allowed := s.opts.Shaper.Allowed
+	result := &geolocationpb.Location{}
+	if allowed("ip_address") {
+		result.IpAddress = location.IPAddress
+	}
+	if allowed("country_code") {
+		result.CountryCode = location.CountryCode
+	}
+	if allowed("country") {
+		result.Country = location.Country
+	}
+	if allowed("city") {
+		result.City = location.City
+	}
+	if allowed("latitude") {
+		result.Latitude = location.Latitude
+	}
+	if allowed("longitude") {
+		result.Longitude = location.Longitude
+	}
+	if allowed("mystery_value") {
+		result.MysteryValue = location.MysteryValue
+	}
+	if allowed("quality_score") {
+		result.QualityScore = int32(location.QualityScore)
+	}
+	if allowed("quality_flags") {
+		result.QualityFlags = location.QualityFlags
+	}
+	if allowed("address_class") {
+		result.AddressClass = location.AddressClass
+	}
+
+	return result
+}
+
+func toLocationStatistics(item models.LocationStatistics) *geolocationpb.LocationStatistics {
+	stat := &geolocationpb.LocationStatistics{
+		CountryCode: item.CountryCode,
+		Country:     item.Country,
+		City:        item.City,
+		Count:       item.Count,
+		Latitude:    item.Latitude,
+		Longitude:   item.Longitude,
+	}
+	if item.MysteryValue != nil {
+		stat.MysteryValue = &geolocationpb.MysteryValueStatistics{
+			Min: item.MysteryValue.Min,
+			Max: item.MysteryValue.Max,
+			Avg: item.MysteryValue.Avg,
+			Sum: item.MysteryValue.Sum,
+		}
+	}
+
+	return stat
+}
@@ -0,0 +1,520 @@
+package grpcapi
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"log/slog"
+	"net"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	"vio/internal/auth"
+	"vio/internal/grpcapi/geolocationpb"
+	"vio/internal/lib/ratelimit"
+	"vio/internal/processes"
+	"vio/internal/shape"
+)
+
+var locationColumns = []string{"ip_address", "country_code", "country", "city", "latitude", "longitude", "mystery_value", "quality_score", "quality_flags"}
+
+// startServer serves the gRPC API in memory and returns a connection to it.
+func startServer(t *testing.T, db *sql.DB, store *auth.Store, opts Options) *grpc.ClientConn {
+	t.Helper()
+
+	listener := bufconn.Listen(1024 * 1024)
+	srv := NewServer(db, store, slog.New(slog.NewTextHandler(io.Discard, nil)), opts)
+	go func() {
+		_ = srv.Serve(listener)
+	}()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return conn
+}
+
+func TestService_Lookup(t *testing.T) {
+	tests := []struct {
+		name      string
+		ipAddress string
+		rows      *sqlmock.Rows
+		want      *geolocationpb.Location
+		wantCode  codes.Code
+	}{
+		{
+			name:      "Found",
+			ipAddress: "70.95.73.73",
+			rows: sqlmock.NewRows(locationColumns).
+				AddRow("70.95.73.73", "TL", "Saudi Arabia", "Gradymouth", -49.16675918861615, -86.05920084416894, 2559997162, 70, "{country_mismatch}"),
+			want: &geolocationpb.Location{
+				IpAddress:    "70.95.73.73",
+				CountryCode:  "TL",
+				Country:      "Saudi Arabia",
+				City:         "Gradymouth",
+				Latitude:     -49.16675918861615,
+				Longitude:    -86.05920084416894,
+				MysteryValue: 2559997162,
+				QualityScore: 70,
+				QualityFlags: []string{"country_mismatch"},
+			},
+			wantCode: codes.OK,
+		},
+		{
+			name:      "Not found",
+			ipAddress: "1.1.1.1",
+			rows:      sqlmock.NewRows(locationColumns),
+			wantCode:  codes.NotFound,
+		},
+		{
+			name:      "Invalid IP address",
+			ipAddress: "1.1.1",
+			wantCode:  codes.InvalidArgument,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer db.Close()
+
+			if tt.rows != nil {
+				mock.ExpectQuery("SELECT (.+) FROM location WHERE ip_address = (.+)").
+					WithArgs(tt.ipAddress).
+					WillReturnRows(tt.rows)
+			}
+
+			client := geolocationpb.NewGeolocationClient(startServer(t, db, nil, Options{}))
+			got, err := client.Lookup(context.Background(), &geolocationpb.LookupRequest{IpAddress: tt.ipAddress})
+
+			assert.Equal(t, tt.wantCode, status.Code(err))
+			if diff := cmp.Diff(tt.want, got, protocmp.Transform()); diff != "" {
+				t.Fatal("result mismatch\n", diff)
+			}
+			if err = mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
+
+func TestService_BatchLookup(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT (.+) FROM location WHERE ip_address = (.+)").
+		WithArgs("70.95.73.73").
+		WillReturnRows(sqlmock.NewRows(locationColumns).
+			AddRow("70.95.73.73", "TL", "Saudi Arabia", "Gradymouth", -49.16675918861615, -86.05920084416894, 2559997162, 100, "{}"))
+	mock.ExpectQuery("SELECT (.+) FROM location WHERE ip_address = (.+)").
+		WithArgs("1.1.1.1").
+		WillReturnRows(sqlmock.NewRows(locationColumns))
+
+	client := geolocationpb.NewGeolocationClient(startServer(t, db, nil, Options{}))
+	stream, err := client.BatchLookup(context.Background())
+	require.NoError(t, err)
+
+	for _, ipAddress := range []string{"70.95.73.73", "1.1.1", "1.1.1.1"} {
+		require.NoError(t, stream.Send(&geolocationpb.LookupRequest{IpAddress: ipAddress}))
+	}
+	require.NoError(t, stream.CloseSend())
+
+	var got []*geolocationpb.LookupResponse
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		got = append(got, resp)
+	}
+
+	want := []*geolocationpb.LookupResponse{
+		{
+			IpAddress: "70.95.73.73",
+			Location: &geolocationpb.Location{
+				IpAddress:    "70.95.73.73",
+				CountryCode:  "TL",
+				Country:      "Saudi Arabia",
+				City:         "Gradymouth",
+				Latitude:     -49.16675918861615,
+				Longitude:    -86.05920084416894,
+				MysteryValue: 2559997162,
+				QualityScore: 100,
+			},
+		},
+		{IpAddress: "1.1.1", Error: "invalid IP address"},
+		{IpAddress: "1.1.1.1", Error: "location not found"},
+	}
+	if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+		t.Fatal("result mismatch\n", diff)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestService_Stats(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	columns := []string{"country_code", "country", "city", "count", "x", "y", "z", "min", "max", "avg", "sum"}
+	mock.ExpectQuery(`SELECT (.+) FROM location GROUP BY 1, 2, 3 ORDER BY COUNT\(\*\) DESC, 1, 2, 3 LIMIT \$1`).
+		WithArgs(5).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow("TL", "Saudi Arabia", "Gradymouth", 2, 1.0, 0.0, 0.0, 10, 30, 20.0, 40))
+
+	client := geolocationpb.NewGeolocationClient(startServer(t, db, nil, Options{}))
+	got, err := client.Stats(context.Background(), &geolocationpb.StatsRequest{
+		Group: geolocationpb.StatsRequest_GROUP_CITY,
+		Top:   5,
+	})
+	require.NoError(t, err)
+
+	want := &geolocationpb.StatsResponse{
+		Items: []*geolocationpb.LocationStatistics{
+			{
+				CountryCode:  "TL",
+				Country:      "Saudi Arabia",
+				City:         "Gradymouth",
+				Count:        2,
+				MysteryValue: &geolocationpb.MysteryValueStatistics{Min: 10, Max: 30, Avg: 20, Sum: 40},
+			},
+		},
+	}
+	if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+		t.Fatal("result mismatch\n", diff)
+	}
+
+	_, err = client.Stats(context.Background(), &geolocationpb.StatsRequest{Top: -1})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestNewServer_Health(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	// Health checking does not require API keys.
+	client := healthpb.NewHealthClient(startServer(t, db, auth.NewStore(db), Options{}))
+	resp, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{
+		Service: geolocationpb.Geolocation_ServiceDesc.ServiceName,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.GetStatus())
+}
+
+func TestAuthenticator(t *testing.T) {
+	const secret = "vio_test-secret"
+
+	tests := []struct {
+		name     string
+		key      string
+		scopes   string
+		wantCode codes.Code
+	}{
+		{
+			name:     "Missing key",
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name:     "Scope is not granted",
+			key:      secret,
+			scopes:   "{lookup}",
+			wantCode: codes.PermissionDenied,
+		},
+		{
+			name:     "Valid key",
+			key:      secret,
+			scopes:   "{batch}",
+			wantCode: codes.OK,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer db.Close()
+
+			if tt.scopes != "" {
+				columns := []string{"id", "name", "key_prefix", "scopes", "daily_quota", "created_at", "revoked_at"}
+				mock.ExpectQuery(regexp.QuoteMeta(auth.SQLSelectAPIKeyByHash)).
+					WillReturnRows(sqlmock.NewRows(columns).AddRow(1, "test", "vio_test", tt.scopes, 0, time.Now(), nil))
+			}
+			// The stream sends no IP addresses, so the quota is not consumed.
+			client := geolocationpb.NewGeolocationClient(startServer(t, db, auth.NewStore(db), Options{}))
+			stream, err := client.BatchLookup(metadata.AppendToOutgoingContext(context.Background(), "x-api-key", tt.key))
+			require.NoError(t, err)
+			require.NoError(t, stream.CloseSend())
+
+			_, err = stream.Recv()
+			if tt.wantCode == codes.OK {
+				assert.Equal(t, io.EOF, err)
+			} else {
+				assert.Equal(t, tt.wantCode, status.Code(err))
+			}
+			if err = mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
+
+func TestService_responseFields(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT (.+) FROM location WHERE ip_address = (.+)").
+		WithArgs("70.95.73.73").
+		WillReturnRows(sqlmock.NewRows(locationColumns).
+			AddRow("70.95.73.73", "TL", "Saudi Arabia", "Gradymouth", -49.16675918861615, -86.05920084416894, 2559997162, 100, "{}"))
+	columns := []string{"country_code", "country", "city", "count", "x", "y", "z", "min", "max", "avg", "sum"}
+	mock.ExpectQuery(`SELECT (.+) FROM location GROUP BY 1, 2 ORDER BY COUNT\(\*\) DESC, 1, 2`).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow("TL", "Saudi Arabia", "", 2, 1.0, 0.0, 0.0, 10, 30, 20.0, 40))
+
+	// The allow-list hides mystery_value.
+	shaper, err := shape.New([]string{"ip_address", "country_code", "country", "city", "latitude", "longitude"})
+	require.NoError(t, err)
+	client := geolocationpb.NewGeolocationClient(startServer(t, db, nil, Options{Shaper: shaper}))
+
+	gotLocation, err := client.Lookup(context.Background(), &geolocationpb.LookupRequest{IpAddress: "70.95.73.73"})
+	require.NoError(t, err)
+	wantLocation := &geolocationpb.Location{
+		IpAddress:   "70.95.73.73",
+		CountryCode: "TL",
+		Country:     "Saudi Arabia",
+		City:        "Gradymouth",
+		Latitude:    -49.16675918861615,
+		Longitude:   -86.05920084416894,
+	}
+	if diff := cmp.Diff(wantLocation, gotLocation, protocmp.Transform()); diff != "" {
+		t.Fatal("location mismatch\n", diff)
+	}
+
+	gotStatistics, err := client.Stats(context.Background(), &geolocationpb.StatsRequest{})
+	require.NoError(t, err)
+	wantStatistics := &geolocationpb.StatsResponse{
+		Items: []*geolocationpb.LocationStatistics{
+			{CountryCode: "TL", Country: "Saudi Arabia", Count: 2},
+		},
+	}
+	if diff := cmp.Diff(wantStatistics, gotStatistics, protocmp.Transform()); diff != "" {
+		t.Fatal("statistics mismatch\n", diff)
+	}
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestAuthenticator_streamQuota(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	columns := []string{"id", "name", "key_prefix", "scopes", "daily_quota", "created_at", "revoked_at"}
+	mock.ExpectQuery(regexp.QuoteMeta(auth.SQLSelectAPIKeyByHash)).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(1, "test", "vio_test", "{batch}", 1, time.Now(), nil))
+	mock.ExpectQuery(regexp.QuoteMeta(auth.SQLConsumeQuota)).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"requests"}).AddRow(1))
+	mock.ExpectQuery("SELECT (.+) FROM location WHERE ip_address = (.+)").
+		WithArgs("70.95.73.73").
+		WillReturnRows(sqlmock.NewRows(locationColumns).
+			AddRow("70.95.73.73", "TL", "Saudi Arabia", "Gradymouth", -49.16675918861615, -86.05920084416894, 2559997162, 100, "{}"))
+	mock.ExpectQuery(regexp.QuoteMeta(auth.SQLConsumeQuota)).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"requests"}).AddRow(2))
+
+	client := geolocationpb.NewGeolocationClient(startServer(t, db, auth.NewStore(db), Options{}))
+	stream, err := client.BatchLookup(metadata.AppendToOutgoingContext(context.Background(), "x-api-key", "vio_test-secret"))
+	require.NoError(t, err)
+
+	require.NoError(t, stream.Send(&geolocationpb.LookupRequest{IpAddress: "70.95.73.73"}))
+	resp, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, "70.95.73.73", resp.GetLocation().GetIpAddress())
+
+	// The second IP address exceeds the daily quota of one request.
+	require.NoError(t, stream.Send(&geolocationpb.LookupRequest{IpAddress: "70.95.73.73"}))
+	_, err = stream.Recv()
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestAuthenticator_rateLimit(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	columns := []string{"id", "name", "key_prefix", "scopes", "daily_quota", "created_at", "revoked_at"}
+	mock.ExpectQuery(regexp.QuoteMeta(auth.SQLSelectAPIKeyByHash)).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(1, "test", "vio_test", "{lookup}", 10, time.Now(), nil))
+
+	// The rate limit of the key is exhausted, so the call is rejected before the quota is consumed.
+	byKey := ratelimit.New(0, 1)
+	byKey.Allow("key:1")
+	authenticator := NewAuthenticator(auth.NewStore(db), NewRateLimiter(ratelimit.New(0, 1), byKey), slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-api-key", "vio_test-secret"))
+	info := &grpc.UnaryServerInfo{FullMethod: geolocationpb.Geolocation_Lookup_FullMethodName}
+	_, err = authenticator.Unary()(ctx, &geolocationpb.LookupRequest{}, info, func(ctx context.Context, req any) (any, error) {
+		t.Error("handler of a rate limited call is called")
+		return nil, nil
+	})
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestService_queryTimeout(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT (.+) FROM location WHERE ip_address = (.+)").
+		WithArgs("70.95.73.73").
+		WillDelayFor(200 * time.Millisecond).
+		WillReturnRows(sqlmock.NewRows(locationColumns).
+			AddRow("70.95.73.73", "TL", "Saudi Arabia", "Gradymouth", -49.16675918861615, -86.05920084416894, 2559997162, 100, "{}"))
+
+	client := geolocationpb.NewGeolocationClient(startServer(t, db, nil, Options{QueryTimeout: 20 * time.Millisecond}))
+	started := time.Now()
+	_, err = client.Lookup(context.Background(), &geolocationpb.LookupRequest{IpAddress: "70.95.73.73"})
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+	assert.Less(t, time.Since(started), 200*time.Millisecond, "query is not cancelled")
+}
+
+func TestService_bogons(t *testing.T) {
+	tests := []struct {
+		name     string
+		bogons   processes.BogonPolicy
+		rows     *sqlmock.Rows
+		want     *geolocationpb.Location
+		wantCode codes.Code
+	}{
+		{
+			name:     "Rejected address is not looked up",
+			bogons:   processes.BogonsReject,
+			wantCode: codes.FailedPrecondition,
+		},
+		{
+			name:   "Tagged address has the class",
+			bogons: processes.BogonsTag,
+			rows: sqlmock.NewRows(locationColumns).
+				AddRow("192.168.1.10", "NL", "Netherlands", "Amsterdam", 52.37, 4.89, 1, 70, "{reserved_address}"),
+			want: &geolocationpb.Location{
+				IpAddress:    "192.168.1.10",
+				CountryCode:  "NL",
+				Country:      "Netherlands",
+				City:         "Amsterdam",
+				Latitude:     52.37,
+				Longitude:    4.89,
+				MysteryValue: 1,
+				QualityScore: 70,
+				QualityFlags: []string{"reserved_address"},
+				AddressClass: "private",
+			},
+			wantCode: codes.OK,
+		},
+		{
+			name:     "Tagged address is not found",
+			bogons:   processes.BogonsTag,
+			rows:     sqlmock.NewRows(locationColumns),
+			wantCode: codes.FailedPrecondition,
+		},
+		{
+			name:     "Accepted address is not found",
+			bogons:   processes.BogonsAccept,
+			rows:     sqlmock.NewRows(locationColumns),
+			wantCode: codes.NotFound,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer db.Close()
+
+			if tt.rows != nil {
+				mock.ExpectQuery("SELECT (.+) FROM location WHERE ip_address = (.+)").
+					WithArgs("192.168.1.10").
+					WillReturnRows(tt.rows)
+			}
+
+			client := geolocationpb.NewGeolocationClient(startServer(t, db, nil, Options{Bogons: tt.bogons}))
+			got, err := client.Lookup(context.Background(), &geolocationpb.LookupRequest{IpAddress: "192.168.1.10"})
+			assert.Equal(t, tt.wantCode, status.Code(err))
+			if diff := cmp.Diff(tt.want, got, protocmp.Transform()); diff != "" {
+				t.Fatal("location mismatch\n", diff)
+			}
+
+			if err = mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
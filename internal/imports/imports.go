@@ -0,0 +1,266 @@
+package imports
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"vio/internal/lib/logger/sl"
+	"vio/internal/models"
+	"vio/internal/processes"
+)
+
+// defaultKeepFinished is the number of finished jobs kept when Options.KeepFinished is not set.
+const defaultKeepFinished = 100
+
+const (
+	StatusRunning   = "running"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+	StatusCancelled = "cancelled"
+)
+
+var (
+	// ErrJobNotFound is returned when the job with the given ID does not exist.
+	ErrJobNotFound = errors.New("import job not found")
+	// ErrJobFinished is returned when a finished job is cancelled.
+	ErrJobFinished = errors.New("import job is already finished")
+	// ErrInvalidPath is returned when the source path does not exist or is outside of the source directory.
+	ErrInvalidPath = errors.New("invalid path")
+)
+
+// importFunc loads the files of the path into the database.
+type importFunc func(ctx context.Context, db *sql.DB, path string, opts processes.ImportOptions, progress *processes.Progress) (*models.LoadStatistics, error)
+
+// Options of the import jobs.
+type Options struct {
+	// SourceDir is the directory server-side paths are resolved in.
+	SourceDir string
+	// UploadDir keeps uploaded files until their import is finished, empty means the system temporary directory.
+	UploadDir string
+	// Import are the options of the imports.
+	Import processes.ImportOptions
+	// KeepFinished is the number of the latest finished jobs kept in memory, 100 by default.
+	KeepFinished int
+}
+
+// Manager runs imports asynchronously and keeps their state in memory.
+type Manager struct {
+	ctx  context.Context
+	db   *sql.DB
+	log  *slog.Logger
+	opts Options
+	run  importFunc
+
+	mu   sync.Mutex
+	jobs map[int64]*job
+	// finished are the IDs of the finished jobs in the order of finishing.
+	finished []int64
+	nextID   int64
+	wg       sync.WaitGroup
+}
+
+type job struct {
+	models.ImportJob
+	progress *processes.Progress
+	cancel   context.CancelFunc
+}
+
+// NewManager creates a manager, running jobs are cancelled when the context is done.
+func NewManager(ctx context.Context, db *sql.DB, log *slog.Logger, opts Options) *Manager {
+	if opts.KeepFinished <= 0 {
+		opts.KeepFinished = defaultKeepFinished
+	}
+
+	return &Manager{
+		ctx:  ctx,
+		db:   db,
+		log:  log,
+		opts: opts,
+		run:  processes.Import,
+		jobs: make(map[int64]*job),
+	}
+}
+
+// StartPath starts the import of the file or the directory relative to the source directory.
+func (m *Manager) StartPath(path string) (models.ImportJob, error) {
+	if !filepath.IsLocal(path) {
+		return models.ImportJob{}, fmt.Errorf("%w: must be relative to the source directory", ErrInvalidPath)
+	}
+	fullPath := filepath.Join(m.opts.SourceDir, path)
+	if _, err := os.Stat(fullPath); err != nil {
+		return models.ImportJob{}, fmt.Errorf("%w: %v", ErrInvalidPath, err)
+	}
+
+	return m.start(path, fullPath, nil), nil
+}
+
+// StartUpload saves the uploaded CSV file and starts its import, the file is removed when the job is finished.
+func (m *Manager) StartUpload(name string, r io.Reader) (models.ImportJob, error) {
+	dir, err := os.MkdirTemp(m.opts.UploadDir, "import-")
+	if err != nil {
+		return models.ImportJob{}, err
+	}
+	cleanup := func() {
+		if err := os.RemoveAll(dir); err != nil {
+			m.log.Error("failed to remove uploaded file", sl.Err(err))
+		}
+	}
+
+	if err := saveFile(filepath.Join(dir, "upload.csv"), r); err != nil {
+		cleanup()
+		return models.ImportJob{}, err
+	}
+
+	return m.start("upload:"+filepath.Base(name), dir, cleanup), nil
+}
+
+// Get returns the state of the job.
+func (m *Manager) Get(id int64) (models.ImportJob, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	j, ok := m.jobs[id]
+	if !ok {
+		return models.ImportJob{}, ErrJobNotFound
+	}
+
+	return j.snapshot(), nil
+}
+
+// List returns the state of the running and the kept finished jobs, the latest first.
+func (m *Manager) List() []models.ImportJob {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	result := make([]models.ImportJob, 0, len(m.jobs))
+	for _, j := range m.jobs {
+		result = append(result, j.snapshot())
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID > result[j].ID
+	})
+
+	return result
+}
+
+// Cancel stops the running job, locations written before cancellation are kept.
+func (m *Manager) Cancel(id int64) (models.ImportJob, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	j, ok := m.jobs[id]
+	if !ok {
+		return models.ImportJob{}, ErrJobNotFound
+	}
+	if j.Status != StatusRunning {
+		return j.snapshot(), ErrJobFinished
+	}
+	j.cancel()
+
+	return j.snapshot(), nil
+}
+
+// Wait blocks until all jobs are finished.
+func (m *Manager) Wait() {
+	m.wg.Wait()
+}
+
+func (m *Manager) start(source, path string, cleanup func()) models.ImportJob {
+	ctx, cancel := context.WithCancel(m.ctx)
+
+	m.mu.Lock()
+	m.nextID++
+	j := &job{
+		ImportJob: models.ImportJob{
+			ID:        m.nextID,
+			Source:    source,
+			Status:    StatusRunning,
+			CreatedAt: time.Now().UTC(),
+		},
+		progress: &processes.Progress{},
+		cancel:   cancel,
+	}
+	m.jobs[j.ID] = j
+	snapshot := j.snapshot()
+	m.mu.Unlock()
+
+	m.log.Info("import started", slog.Int64("job_id", j.ID), slog.String("source", source))
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		defer cancel()
+		if cleanup != nil {
+			defer cleanup()
+		}
+
+		statistics, err := m.run(ctx, m.db, path, m.opts.Import, j.progress)
+		m.finish(j, statistics, err, ctx.Err() != nil)
+	}()
+
+	return snapshot
+}
+
+// finish records the result of the job and forgets the oldest finished jobs over the KeepFinished limit.
+func (m *Manager) finish(j *job, statistics *models.LoadStatistics, err error, cancelled bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	finishedAt := time.Now().UTC()
+	j.FinishedAt = &finishedAt
+	j.Statistics = statistics
+
+	switch {
+	case cancelled:
+		j.Status = StatusCancelled
+	case err != nil:
+		j.Status = StatusFailed
+		j.Error = err.Error()
+	default:
+		j.Status = StatusCompleted
+	}
+
+	m.finished = append(m.finished, j.ID)
+	if over := len(m.finished) - m.opts.KeepFinished; over > 0 {
+		for _, id := range m.finished[:over] {
+			delete(m.jobs, id)
+		}
+		m.finished = append([]int64(nil), m.finished[over:]...)
+	}
+
+	if err != nil && !cancelled {
+		m.log.Error("import failed", slog.Int64("job_id", j.ID), sl.Err(err))
+	} else {
+		m.log.Info("import finished", slog.Int64("job_id", j.ID), slog.String("status", j.Status))
+	}
+}
+
+// snapshot must be called with the manager lock held.
+func (j *job) snapshot() models.ImportJob {
+	result := j.ImportJob
+	result.Progress = j.progress.Snapshot()
+
+	return result
+}
+
+func saveFile(path string, r io.Reader) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(file, r); err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	return file.Close()
+}
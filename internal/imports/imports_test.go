@@ -0,0 +1,133 @@
+package imports
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vio/internal/models"
+	"vio/internal/processes"
+)
+
+func newTestManager(t *testing.T, run importFunc) *Manager {
+	t.Helper()
+
+	m := NewManager(context.Background(), nil, slog.New(slog.NewTextHandler(io.Discard, nil)), Options{
+		SourceDir: t.TempDir(),
+		UploadDir: t.TempDir(),
+	})
+	m.run = run
+
+	return m
+}
+
+func TestManager_StartUpload(t *testing.T) {
+	var gotData string
+	m := newTestManager(t, func(_ context.Context, _ *sql.DB, path string, _ processes.ImportOptions, _ *processes.Progress) (*models.LoadStatistics, error) {
+		data, err := os.ReadFile(filepath.Join(path, "upload.csv"))
+		gotData = string(data)
+		return &models.LoadStatistics{Accepted: 1, Total: 1}, err
+	})
+
+	job, err := m.StartUpload("locations.csv", strings.NewReader("ip_address\n1.1.1.1\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "upload:locations.csv", job.Source)
+	assert.Equal(t, StatusRunning, job.Status)
+
+	m.Wait()
+
+	job, err = m.Get(job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusCompleted, job.Status)
+	assert.Equal(t, &models.LoadStatistics{Accepted: 1, Total: 1}, job.Statistics)
+	assert.NotNil(t, job.FinishedAt)
+	assert.Equal(t, "ip_address\n1.1.1.1\n", gotData)
+
+	// The uploaded file is removed when the job is finished.
+	entries, err := os.ReadDir(m.opts.UploadDir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestManager_StartPath(t *testing.T) {
+	m := newTestManager(t, func(_ context.Context, _ *sql.DB, _ string, _ processes.ImportOptions, _ *processes.Progress) (*models.LoadStatistics, error) {
+		return nil, errors.New("record on line 2: wrong number of fields")
+	})
+	require.NoError(t, os.WriteFile(filepath.Join(m.opts.SourceDir, "data.csv"), nil, 0o600))
+
+	_, err := m.StartPath("../data.csv")
+	assert.ErrorIs(t, err, ErrInvalidPath)
+
+	_, err = m.StartPath("missing.csv")
+	assert.ErrorIs(t, err, ErrInvalidPath)
+
+	job, err := m.StartPath("data.csv")
+	require.NoError(t, err)
+	m.Wait()
+
+	job, err = m.Get(job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusFailed, job.Status)
+	assert.Equal(t, "record on line 2: wrong number of fields", job.Error)
+}
+
+func TestManager_KeepFinished(t *testing.T) {
+	m := newTestManager(t, func(_ context.Context, _ *sql.DB, _ string, _ processes.ImportOptions, _ *processes.Progress) (*models.LoadStatistics, error) {
+		return &models.LoadStatistics{}, nil
+	})
+	m.opts.KeepFinished = 2
+	require.NoError(t, os.WriteFile(filepath.Join(m.opts.SourceDir, "data.csv"), nil, 0o600))
+
+	for i := 0; i < 3; i++ {
+		_, err := m.StartPath("data.csv")
+		require.NoError(t, err)
+		m.Wait()
+	}
+
+	// The oldest finished job is forgotten.
+	_, err := m.Get(1)
+	assert.ErrorIs(t, err, ErrJobNotFound)
+	jobs := m.List()
+	require.Len(t, jobs, 2)
+	assert.Equal(t, int64(3), jobs[0].ID)
+	assert.Equal(t, int64(2), jobs[1].ID)
+}
+
+func TestManager_Cancel(t *testing.T) {
+	started := make(chan struct{})
+	m := newTestManager(t, func(ctx context.Context, _ *sql.DB, _ string, _ processes.ImportOptions, _ *processes.Progress) (*models.LoadStatistics, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	require.NoError(t, os.Mkdir(filepath.Join(m.opts.SourceDir, "batch"), 0o700))
+
+	job, err := m.StartPath("batch")
+	require.NoError(t, err)
+	<-started
+
+	_, err = m.Cancel(job.ID)
+	require.NoError(t, err)
+	m.Wait()
+
+	job, err = m.Get(job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusCancelled, job.Status)
+
+	_, err = m.Cancel(job.ID)
+	assert.ErrorIs(t, err, ErrJobFinished)
+
+	_, err = m.Cancel(job.ID + 1)
+	assert.ErrorIs(t, err, ErrJobNotFound)
+
+	assert.Len(t, m.List(), 1)
+}
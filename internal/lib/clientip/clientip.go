@@ -0,0 +1,147 @@
+package clientip
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// ErrNoClientIP is returned when the client address can not be determined.
+var ErrNoClientIP = errors.New("client IP address can not be determined")
+
+// Resolver determines the client IP address of the request.
+// Proxy headers (Forwarded, X-Forwarded-For, X-Real-IP) are honoured only
+// when the request comes from one of the trusted proxies.
+type Resolver struct {
+	trusted []netip.Prefix
+}
+
+// NewResolver creates a resolver trusting the given proxies, each one is a CIDR or a single IP address.
+func NewResolver(trustedProxies []string) (*Resolver, error) {
+	r := &Resolver{
+		trusted: make([]netip.Prefix, 0, len(trustedProxies)),
+	}
+	for _, proxy := range trustedProxies {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+		if !strings.Contains(proxy, "/") {
+			addr, err := netip.ParseAddr(proxy)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: %w", proxy, err)
+			}
+			addr = addr.Unmap()
+			r.trusted = append(r.trusted, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", proxy, err)
+		}
+		r.trusted = append(r.trusted, prefix.Masked())
+	}
+
+	return r, nil
+}
+
+// ClientIP returns the IP address of the client that sent the request.
+func (r *Resolver) ClientIP(req *http.Request) (netip.Addr, error) {
+	remote, ok := parseHost(req.RemoteAddr)
+	if !ok {
+		return netip.Addr{}, ErrNoClientIP
+	}
+	if !r.isTrusted(remote) {
+		return remote, nil
+	}
+
+	if values := req.Header.Values("Forwarded"); len(values) > 0 {
+		return r.fromChain(remote, parseForwarded(values)), nil
+	}
+	if values := req.Header.Values("X-Forwarded-For"); len(values) > 0 {
+		return r.fromChain(remote, splitList(values)), nil
+	}
+	if value := req.Header.Get("X-Real-IP"); value != "" {
+		if addr, ok := parseHost(strings.TrimSpace(value)); ok {
+			return addr, nil
+		}
+	}
+
+	return remote, nil
+}
+
+// fromChain walks the chain of forwarded addresses from the nearest proxy
+// and returns the first address that is not a trusted proxy.
+func (r *Resolver) fromChain(remote netip.Addr, chain []string) netip.Addr {
+	client := remote
+	for i := len(chain) - 1; i >= 0; i-- {
+		addr, ok := parseHost(chain[i])
+		if !ok {
+			// Unknown or obfuscated identifier, the last trusted hop is the best known address.
+			return client
+		}
+		client = addr
+		if !r.isTrusted(addr) {
+			return addr
+		}
+	}
+
+	return client
+}
+
+func (r *Resolver) isTrusted(addr netip.Addr) bool {
+	for _, prefix := range r.trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseForwarded extracts "for" values of the Forwarded header (RFC 7239).
+func parseForwarded(values []string) []string {
+	var chain []string
+	for _, element := range splitList(values) {
+		for _, pair := range strings.Split(element, ";") {
+			key, value, found := strings.Cut(strings.TrimSpace(pair), "=")
+			if !found || !strings.EqualFold(key, "for") {
+				continue
+			}
+			chain = append(chain, strings.Trim(value, `"`))
+		}
+	}
+
+	return chain
+}
+
+func splitList(values []string) []string {
+	var items []string
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+	}
+
+	return items
+}
+
+// parseHost parses an IP address optionally followed by a port, IPv6 may be in brackets.
+func parseHost(value string) (netip.Addr, bool) {
+	if host, _, err := net.SplitHostPort(value); err == nil {
+		value = host
+	}
+	value = strings.TrimSuffix(strings.TrimPrefix(value, "["), "]")
+
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+
+	return addr.Unmap().WithZone(""), true
+}
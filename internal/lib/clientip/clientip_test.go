@@ -0,0 +1,112 @@
+package clientip
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolver_ClientIP(t *testing.T) {
+	resolver, err := NewResolver([]string{"10.0.0.0/8", "192.0.2.1"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string]string
+		want       string
+		wantError  bool
+	}{
+		{
+			name:       "Direct connection",
+			remoteAddr: "70.95.73.73:54321",
+			want:       "70.95.73.73",
+		},
+		{
+			name:       "Headers from untrusted client are ignored",
+			remoteAddr: "70.95.73.73:54321",
+			headers:    map[string]string{"X-Forwarded-For": "1.2.3.4", "X-Real-IP": "1.2.3.4"},
+			want:       "70.95.73.73",
+		},
+		{
+			name:       "X-Forwarded-For from trusted proxy",
+			remoteAddr: "10.1.2.3:54321",
+			headers:    map[string]string{"X-Forwarded-For": "1.2.3.4, 70.95.73.73, 10.0.0.5"},
+			want:       "70.95.73.73",
+		},
+		{
+			name:       "All hops are trusted",
+			remoteAddr: "10.1.2.3:54321",
+			headers:    map[string]string{"X-Forwarded-For": "10.0.0.7, 10.0.0.5"},
+			want:       "10.0.0.7",
+		},
+		{
+			name:       "Forwarded from trusted proxy",
+			remoteAddr: "192.0.2.1:54321",
+			headers:    map[string]string{"Forwarded": `for=70.95.73.73;proto=https, for="[2001:db8:cafe::17]:4711"`},
+			want:       "2001:db8:cafe::17",
+		},
+		{
+			name:       "Forwarded has priority over X-Forwarded-For",
+			remoteAddr: "192.0.2.1:54321",
+			headers:    map[string]string{"Forwarded": "for=70.95.73.73", "X-Forwarded-For": "1.2.3.4"},
+			want:       "70.95.73.73",
+		},
+		{
+			name:       "Obfuscated identifier",
+			remoteAddr: "10.1.2.3:54321",
+			headers:    map[string]string{"Forwarded": "for=unknown"},
+			want:       "10.1.2.3",
+		},
+		{
+			name:       "X-Real-IP from trusted proxy",
+			remoteAddr: "10.1.2.3:54321",
+			headers:    map[string]string{"X-Real-IP": "70.95.73.73"},
+			want:       "70.95.73.73",
+		},
+		{
+			name:       "IPv4-mapped IPv6 remote address",
+			remoteAddr: "[::ffff:70.95.73.73]:54321",
+			want:       "70.95.73.73",
+		},
+		{
+			name:       "Invalid remote address",
+			remoteAddr: "pipe",
+			wantError:  true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(http.MethodGet, "/api/geolocation/me", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
+			}
+
+			got, err := resolver.ClientIP(req)
+			if tt.wantError {
+				assert.ErrorIs(t, err, ErrNoClientIP)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got.String())
+		})
+	}
+}
+
+func TestNewResolver(t *testing.T) {
+	_, err := NewResolver([]string{"10.0.0.0/8", " ", "::1"})
+	assert.NoError(t, err)
+
+	_, err = NewResolver([]string{"10.0.0.0/33"})
+	assert.Error(t, err)
+
+	_, err = NewResolver([]string{"proxy.local"})
+	assert.Error(t, err)
+}
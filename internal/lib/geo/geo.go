@@ -0,0 +1,56 @@
+package geo
+
+import (
+	"math"
+)
+
+// EarthRadiusKm is the mean radius of the Earth.
+const EarthRadiusKm = 6371.0
+
+// Point represents a point on the Earth surface in degrees.
+type Point struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// Haversine returns the great-circle distance between two points in kilometers.
+func Haversine(p1, p2 Point) float64 {
+	lat1 := radians(p1.Latitude)
+	lat2 := radians(p2.Latitude)
+	dLat := lat2 - lat1
+	dLon := radians(p2.Longitude - p1.Longitude)
+
+	a := math.Pow(math.Sin(dLat/2), 2) + math.Cos(lat1)*math.Cos(lat2)*math.Pow(math.Sin(dLon/2), 2)
+
+	return 2 * EarthRadiusKm * math.Asin(math.Sqrt(math.Min(1, a)))
+}
+
+// toVector converts the point to a unit vector in Cartesian coordinates.
+func toVector(p Point) [3]float64 {
+	lat := radians(p.Latitude)
+	lon := radians(p.Longitude)
+
+	return [3]float64{
+		math.Cos(lat) * math.Cos(lon),
+		math.Cos(lat) * math.Sin(lon),
+		math.Sin(lat),
+	}
+}
+
+// chordToKm converts the chord length between two unit vectors to the great-circle distance.
+func chordToKm(chord float64) float64 {
+	return 2 * EarthRadiusKm * math.Asin(math.Min(1, chord/2))
+}
+
+// kmToChord converts the great-circle distance to the chord length between two unit vectors.
+func kmToChord(km float64) float64 {
+	if km >= math.Pi*EarthRadiusKm {
+		return 2
+	}
+
+	return 2 * math.Sin(km/(2*EarthRadiusKm))
+}
+
+func radians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
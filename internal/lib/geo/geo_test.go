@@ -0,0 +1,49 @@
+package geo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHaversine(t *testing.T) {
+	tests := []struct {
+		name string
+		p1   Point
+		p2   Point
+		want float64
+	}{
+		{
+			name: "Same point",
+			p1:   Point{Latitude: 52.37, Longitude: 4.89},
+			p2:   Point{Latitude: 52.37, Longitude: 4.89},
+			want: 0,
+		},
+		{
+			name: "Amsterdam - Berlin",
+			p1:   Point{Latitude: 52.3676, Longitude: 4.9041},
+			p2:   Point{Latitude: 52.5200, Longitude: 13.4050},
+			want: 576.1,
+		},
+		{
+			name: "Across the antimeridian",
+			p1:   Point{Latitude: 0, Longitude: 179.5},
+			p2:   Point{Latitude: 0, Longitude: -179.5},
+			want: 111.2,
+		},
+		{
+			name: "Antipodes",
+			p1:   Point{Latitude: 90, Longitude: 0},
+			p2:   Point{Latitude: -90, Longitude: 0},
+			want: 20015.1,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.InDelta(t, tt.want, Haversine(tt.p1, tt.p2), 0.1)
+		})
+	}
+}
@@ -0,0 +1,202 @@
+package geo
+
+import (
+	"container/heap"
+	"math"
+)
+
+// Neighbor is a result of the nearest neighbour search.
+type Neighbor struct {
+	// Index of the point in the slice passed to NewKDTree.
+	Index      int
+	DistanceKm float64
+}
+
+// KDTree is a static k-d tree over points converted to unit vectors,
+// so distances are not distorted near the poles and the antimeridian.
+// The tree is stored in the implicit layout: the median of a range is its root.
+type KDTree struct {
+	vectors [][3]float64
+	indexes []int
+}
+
+// NewKDTree builds a k-d tree over the points.
+func NewKDTree(points []Point) *KDTree {
+	t := &KDTree{
+		vectors: make([][3]float64, len(points)),
+		indexes: make([]int, len(points)),
+	}
+	for i, p := range points {
+		t.vectors[i] = toVector(p)
+		t.indexes[i] = i
+	}
+	t.build(0, len(points), 0)
+
+	return t
+}
+
+// Len returns the number of points in the tree.
+func (t *KDTree) Len() int {
+	return len(t.indexes)
+}
+
+// Nearest returns up to limit points closest to p within radiusKm ordered by distance.
+// A non-positive radius means the distance is not limited.
+func (t *KDTree) Nearest(p Point, radiusKm float64, limit int) []Neighbor {
+	if limit <= 0 || t.Len() == 0 {
+		return []Neighbor{}
+	}
+
+	maxChord := 2.0
+	if radiusKm > 0 {
+		maxChord = kmToChord(radiusKm)
+	}
+
+	s := &search{
+		target:    toVector(p),
+		limit:     limit,
+		maxDistSq: maxChord * maxChord,
+	}
+	t.search(s, 0, len(t.indexes), 0)
+
+	result := make([]Neighbor, s.found.Len())
+	for i := len(result) - 1; i >= 0; i-- {
+		c := heap.Pop(&s.found).(candidate)
+		result[i] = Neighbor{
+			Index:      c.index,
+			DistanceKm: chordToKm(math.Sqrt(c.distSq)),
+		}
+	}
+
+	return result
+}
+
+func (t *KDTree) build(lo, hi, axis int) {
+	if hi-lo <= 1 {
+		return
+	}
+	mid := (lo + hi) / 2
+	t.selectNth(lo, hi, mid, axis)
+
+	next := (axis + 1) % 3
+	t.build(lo, mid, next)
+	t.build(mid+1, hi, next)
+}
+
+// selectNth reorders the range so the element at n is in its sorted position by the axis,
+// smaller elements are before it and greater elements are after it (quickselect).
+func (t *KDTree) selectNth(lo, hi, n, axis int) {
+	for hi-lo > 1 {
+		pivot := t.vectors[(lo+hi)/2][axis]
+		i, j := lo, hi-1
+		for i <= j {
+			for t.vectors[i][axis] < pivot {
+				i++
+			}
+			for t.vectors[j][axis] > pivot {
+				j--
+			}
+			if i <= j {
+				t.swap(i, j)
+				i++
+				j--
+			}
+		}
+
+		switch {
+		case n <= j:
+			hi = j + 1
+		case n >= i:
+			lo = i
+		default:
+			return
+		}
+	}
+}
+
+func (t *KDTree) swap(i, j int) {
+	t.vectors[i], t.vectors[j] = t.vectors[j], t.vectors[i]
+	t.indexes[i], t.indexes[j] = t.indexes[j], t.indexes[i]
+}
+
+func (t *KDTree) search(s *search, lo, hi, axis int) {
+	if lo >= hi {
+		return
+	}
+	mid := (lo + hi) / 2
+	v := t.vectors[mid]
+
+	distSq := squaredDistance(v, s.target)
+	if distSq <= s.maxDistSq {
+		s.add(candidate{index: t.indexes[mid], distSq: distSq})
+	}
+
+	diff := s.target[axis] - v[axis]
+	next := (axis + 1) % 3
+	if diff < 0 {
+		t.search(s, lo, mid, next)
+		if diff*diff <= s.bound() {
+			t.search(s, mid+1, hi, next)
+		}
+	} else {
+		t.search(s, mid+1, hi, next)
+		if diff*diff <= s.bound() {
+			t.search(s, lo, mid, next)
+		}
+	}
+}
+
+type search struct {
+	target    [3]float64
+	limit     int
+	maxDistSq float64
+	found     candidates
+}
+
+// bound returns the squared distance a point must not exceed to get into the result.
+func (s *search) bound() float64 {
+	if s.found.Len() < s.limit {
+		return s.maxDistSq
+	}
+
+	return s.found[0].distSq
+}
+
+func (s *search) add(c candidate) {
+	if s.found.Len() < s.limit {
+		heap.Push(&s.found, c)
+		return
+	}
+	if c.distSq < s.found[0].distSq {
+		s.found[0] = c
+		heap.Fix(&s.found, 0)
+	}
+}
+
+type candidate struct {
+	index  int
+	distSq float64
+}
+
+// candidates is a max-heap by distance.
+type candidates []candidate
+
+func (c candidates) Len() int           { return len(c) }
+func (c candidates) Less(i, j int) bool { return c[i].distSq > c[j].distSq }
+func (c candidates) Swap(i, j int)      { c[i], c[j] = c[j], c[i] }
+func (c *candidates) Push(x any)        { *c = append(*c, x.(candidate)) }
+func (c *candidates) Pop() any {
+	old := *c
+	n := len(old)
+	x := old[n-1]
+	*c = old[:n-1]
+	return x
+}
+
+func squaredDistance(a, b [3]float64) float64 {
+	dx := a[0] - b[0]
+	dy := a[1] - b[1]
+	dz := a[2] - b[2]
+
+	return dx*dx + dy*dy + dz*dz
+}
@@ -0,0 +1,90 @@
+package geo
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKDTree_Nearest(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	points := make([]Point, 5000)
+	for i := range points {
+		points[i] = randomPoint(rnd)
+	}
+	tree := NewKDTree(points)
+	require.Equal(t, len(points), tree.Len())
+
+	for i := 0; i < 100; i++ {
+		target := randomPoint(rnd)
+		radiusKm := rnd.Float64() * 3000
+		limit := 1 + rnd.Intn(20)
+
+		got := tree.Nearest(target, radiusKm, limit)
+		want := bruteForceNearest(points, target, radiusKm, limit)
+
+		require.Len(t, got, len(want))
+		for j := range want {
+			assert.Equal(t, want[j].Index, got[j].Index)
+			assert.InDelta(t, want[j].DistanceKm, got[j].DistanceKm, 1e-6)
+		}
+	}
+}
+
+func TestKDTree_NearestUnlimitedRadius(t *testing.T) {
+	points := []Point{
+		{Latitude: 0, Longitude: 179.9},
+		{Latitude: 0, Longitude: 0},
+		{Latitude: 0, Longitude: -179.9},
+	}
+	tree := NewKDTree(points)
+
+	got := tree.Nearest(Point{Latitude: 0, Longitude: 180}, 0, 2)
+	require.Len(t, got, 2)
+	assert.ElementsMatch(t, []int{0, 2}, []int{got[0].Index, got[1].Index})
+
+	assert.Empty(t, tree.Nearest(Point{}, 0, 0))
+	assert.Empty(t, NewKDTree(nil).Nearest(Point{}, 0, 10))
+}
+
+func BenchmarkKDTree_Nearest(b *testing.B) {
+	rnd := rand.New(rand.NewSource(1))
+	points := make([]Point, 1_000_000)
+	for i := range points {
+		points[i] = randomPoint(rnd)
+	}
+	tree := NewKDTree(points)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tree.Nearest(randomPoint(rnd), 500, 10)
+	}
+}
+
+func randomPoint(rnd *rand.Rand) Point {
+	return Point{
+		Latitude:  rnd.Float64()*180 - 90,
+		Longitude: rnd.Float64()*360 - 180,
+	}
+}
+
+func bruteForceNearest(points []Point, target Point, radiusKm float64, limit int) []Neighbor {
+	var result []Neighbor
+	for i, p := range points {
+		distance := Haversine(target, p)
+		if distance <= radiusKm {
+			result = append(result, Neighbor{Index: i, DistanceKm: distance})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].DistanceKm < result[j].DistanceKm
+	})
+	if len(result) > limit {
+		result = result[:limit]
+	}
+
+	return result
+}
@@ -0,0 +1,55 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimiter_Allow(t *testing.T) {
+	now := time.Date(2023, 11, 20, 10, 0, 0, 0, time.UTC)
+	limiter := New(2, 3)
+	limiter.now = func() time.Time { return now }
+
+	for i := 2; i >= 0; i-- {
+		result := limiter.Allow("client")
+		assert.True(t, result.Allowed)
+		assert.Equal(t, 3, result.Limit)
+		assert.Equal(t, i, result.Remaining)
+	}
+
+	result := limiter.Allow("client")
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 500*time.Millisecond, result.RetryAfter)
+	assert.Equal(t, 1500*time.Millisecond, result.Reset)
+
+	// Other keys have their own buckets.
+	assert.True(t, limiter.Allow("other").Allowed)
+
+	now = now.Add(500 * time.Millisecond)
+	result = limiter.Allow("client")
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+}
+
+func TestLimiter_Cleanup(t *testing.T) {
+	now := time.Date(2023, 11, 20, 10, 0, 0, 0, time.UTC)
+	limiter := New(1, 1)
+	limiter.now = func() time.Time { return now }
+
+	limiter.Allow("client")
+	assert.Len(t, limiter.buckets, 1)
+
+	now = now.Add(2 * cleanupInterval)
+	limiter.Allow("other")
+	assert.Len(t, limiter.buckets, 1)
+	assert.Contains(t, limiter.buckets, "other")
+}
+
+func TestCeilSeconds(t *testing.T) {
+	assert.Equal(t, 0, CeilSeconds(0))
+	assert.Equal(t, 1, CeilSeconds(500*time.Millisecond))
+	assert.Equal(t, 1, CeilSeconds(time.Second))
+	assert.Equal(t, 2, CeilSeconds(1500*time.Millisecond))
+}
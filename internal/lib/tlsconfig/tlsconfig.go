@@ -0,0 +1,281 @@
+package tlsconfig
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"vio/internal/lib/logger/sl"
+)
+
+// reloadDelay groups bursts of file events (e.g. the certificate and the key written one by one).
+const reloadDelay = 500 * time.Millisecond
+
+// Options of the TLS server.
+type Options struct {
+	CertFile string
+	KeyFile  string
+	// ClientCAFile is a PEM bundle of CAs to verify client certificates, empty disables client certificates.
+	ClientCAFile string
+	// ClientAuth is one of: none, request, require_any, verify_if_given, require_and_verify.
+	ClientAuth string
+	// MinVersion is one of: 1.0, 1.1, 1.2, 1.3.
+	MinVersion string
+	// CipherSuites are names of the TLS 1.0-1.2 cipher suites, empty means Go defaults.
+	CipherSuites []string
+}
+
+// Reloader serves the certificate and client CAs loaded from files and reloads them when the files change.
+type Reloader struct {
+	opts      Options
+	base      *tls.Config
+	log       *slog.Logger
+	mu        sync.RWMutex
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+}
+
+// NewReloader validates the options and loads the certificate.
+func NewReloader(opts Options, log *slog.Logger) (*Reloader, error) {
+	if opts.CertFile == "" || opts.KeyFile == "" {
+		return nil, errors.New("TLS certificate and key files are required")
+	}
+
+	minVersion, err := ParseMinVersion(opts.MinVersion)
+	if err != nil {
+		return nil, err
+	}
+	cipherSuites, err := ParseCipherSuites(opts.CipherSuites)
+	if err != nil {
+		return nil, err
+	}
+	clientAuth, err := ParseClientAuth(opts.ClientAuth, opts.ClientCAFile != "")
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Reloader{
+		opts: opts,
+		base: &tls.Config{
+			MinVersion:   minVersion,
+			CipherSuites: cipherSuites,
+			ClientAuth:   clientAuth,
+		},
+		log: log,
+	}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Config returns the server TLS config using the latest loaded certificate and client CAs.
+// HTTP/2 is advertised with ALPN, as http.Server and gRPC add it to their copies of the config,
+// which the config returned for every client does not see.
+func (r *Reloader) Config() *tls.Config {
+	cfg := r.base.Clone()
+	cfg.NextProtos = []string{"h2", "http/1.1"}
+	cfg.GetCertificate = func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+		r.mu.RLock()
+		defer r.mu.RUnlock()
+
+		return r.cert, nil
+	}
+	cfg.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		r.mu.RLock()
+		defer r.mu.RUnlock()
+
+		clientCfg := r.base.Clone()
+		clientCfg.Certificates = []tls.Certificate{*r.cert}
+		clientCfg.ClientCAs = r.clientCAs
+		clientCfg.NextProtos = cfg.NextProtos
+
+		return clientCfg, nil
+	}
+
+	return cfg
+}
+
+// Reload loads the certificate and client CAs from the files, the previous ones are kept on error.
+func (r *Reloader) Reload() error {
+	cert, err := tls.LoadX509KeyPair(r.opts.CertFile, r.opts.KeyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+
+	var clientCAs *x509.CertPool
+	if r.opts.ClientCAFile != "" {
+		data, err := os.ReadFile(r.opts.ClientCAFile)
+		if err != nil {
+			return fmt.Errorf("failed to read client CA file: %w", err)
+		}
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(data) {
+			return fmt.Errorf("no certificates found in client CA file %s", r.opts.ClientCAFile)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.cert = &cert
+	r.clientCAs = clientCAs
+
+	return nil
+}
+
+// Watch reloads the files when they change until the context is done.
+// Directories are watched instead of files to follow atomic renames and symlink swaps (e.g. Kubernetes secrets).
+func (r *Reloader) Watch(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	dirs := make(map[string]bool)
+	for _, file := range r.files() {
+		dir := filepath.Dir(file)
+		if dirs[dir] {
+			continue
+		}
+		if err := watcher.Add(dir); err != nil {
+			return err
+		}
+		dirs[dir] = true
+	}
+
+	timer := time.NewTimer(reloadDelay)
+	timer.Stop()
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if r.isWatched(event.Name) {
+				timer.Reset(reloadDelay)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			r.log.Error("TLS files watcher error", sl.Err(err))
+		case <-timer.C:
+			if err := r.Reload(); err != nil {
+				r.log.Error("failed to reload TLS certificate, the previous one is used", sl.Err(err))
+				continue
+			}
+			r.log.Info("TLS certificate reloaded")
+		}
+	}
+}
+
+func (r *Reloader) files() []string {
+	files := []string{r.opts.CertFile, r.opts.KeyFile}
+	if r.opts.ClientCAFile != "" {
+		files = append(files, r.opts.ClientCAFile)
+	}
+
+	return files
+}
+
+// isWatched reports whether the event may change one of the files.
+func (r *Reloader) isWatched(name string) bool {
+	// Kubernetes updates mounted secrets by swapping the ..data symlink.
+	if strings.HasPrefix(filepath.Base(name), "..") {
+		return true
+	}
+	for _, file := range r.files() {
+		if filepath.Clean(name) == filepath.Clean(file) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ParseMinVersion converts the version like "1.2" to the TLS version constant, empty means TLS 1.2.
+func ParseMinVersion(version string) (uint16, error) {
+	switch version {
+	case "1.0":
+		return tls.VersionTLS10, nil
+	case "1.1":
+		return tls.VersionTLS11, nil
+	case "", "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("unknown TLS version %q", version)
+	}
+}
+
+// ParseCipherSuites converts names of cipher suites (e.g. TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256) to their IDs.
+// Only secure cipher suites are accepted, TLS 1.3 suites are not configurable.
+func ParseCipherSuites(names []string) ([]uint16, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	known := make(map[string]uint16)
+	for _, suite := range tls.CipherSuites() {
+		known[suite.Name] = suite.ID
+	}
+
+	ids := make([]uint16, 0, len(names))
+	for _, name := range names {
+		id, ok := known[strings.TrimSpace(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown or insecure cipher suite %q", name)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+// ParseClientAuth converts the client authentication policy name to the TLS constant.
+// When it is empty, client certificates are required and verified if client CAs are set.
+func ParseClientAuth(policy string, hasClientCAs bool) (tls.ClientAuthType, error) {
+	switch policy {
+	case "":
+		if hasClientCAs {
+			return tls.RequireAndVerifyClientCert, nil
+		}
+		return tls.NoClientCert, nil
+	case "none":
+		return tls.NoClientCert, nil
+	case "request":
+		return tls.RequestClientCert, nil
+	case "require_any":
+		return tls.RequireAnyClientCert, nil
+	case "verify_if_given":
+		if !hasClientCAs {
+			return 0, errors.New("client CA file is required to verify client certificates")
+		}
+		return tls.VerifyClientCertIfGiven, nil
+	case "require_and_verify":
+		if !hasClientCAs {
+			return 0, errors.New("client CA file is required to verify client certificates")
+		}
+		return tls.RequireAndVerifyClientCert, nil
+	default:
+		return 0, fmt.Errorf("unknown client auth policy %q", policy)
+	}
+}
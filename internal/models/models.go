@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 // Location represents location.
 // swagger:model
 type Location struct {
@@ -10,13 +12,158 @@ type Location struct {
 	Latitude     float64 `json:"latitude"`
 	Longitude    float64 `json:"longitude"`
 	MysteryValue int64   `json:"mystery_value"`
+	// QualityScore is the data quality of the location from 0 to 100, quality flags lower the score.
+	QualityScore int `json:"quality_score"`
+	// QualityFlags are the issues found in the location data, e.g. missing_city, zero_coordinates.
+	QualityFlags []string `json:"quality_flags,omitempty"`
+	// AddressClass is the class of a special-purpose address (e.g. private, documentation)
+	// returned by the API with the tag bogon policy.
+	AddressClass string `json:"address_class,omitempty"`
+	// CountryOriginal and CityOriginal are the values of the source file changed by the normalization
+	// or the country correction,
+	// they are stored for auditing and are not returned by the API.
+	CountryOriginal string `json:"-"`
+	CityOriginal    string `json:"-"`
+}
+
+// ReservedAddress is the response for a special-purpose address handled as reserved by the bogon policy.
+// swagger:model
+type ReservedAddress struct {
+	IPAddress string `json:"ip_address"`
+	// AddressClass is one of: private, loopback, link_local, multicast, unspecified, documentation, reserved.
+	AddressClass string `json:"address_class"`
+	Message      string `json:"message"`
 }
 
 // LoadStatistics information about load data.
+// swagger:model
 type LoadStatistics struct {
+	// Status is one of: completed, completed_with_errors, aborted.
+	Status     string `json:"status,omitempty"`
 	LoadTime   string `json:"load_time"`
 	FilesCount int64  `json:"files_count"`
 	Accepted   int64  `json:"accepted"`
 	Discarded  int64  `json:"discarded"`
 	Total      int64  `json:"total"`
+	// Written is the number of locations written to the database.
+	Written int64 `json:"written"`
+	// Retries is the number of writes repeated after transient database errors.
+	Retries int64 `json:"retries"`
+	// Failed is the number of locations not written due to permanent errors or exhausted retries.
+	Failed int64 `json:"failed"`
+	// Reserved is the number of special-purpose addresses discarded or tagged by the bogon policy.
+	Reserved int64 `json:"reserved,omitempty"`
+	// Normalized is the number of records whose country or city is changed by the normalization.
+	Normalized int64 `json:"normalized,omitempty"`
+	// LowQuality is the number of records discarded with a quality score below the minimum.
+	LowQuality int64 `json:"low_quality,omitempty"`
+	// CountryMismatches is the number of records whose country code and country name disagree.
+	CountryMismatches int64 `json:"country_mismatches,omitempty"`
+	// CountryCorrections is the number of mismatched records corrected by the country policy.
+	CountryCorrections int64 `json:"country_corrections,omitempty"`
+	// Duplicates is the number of records of an IP address repeated in the files.
+	Duplicates int64 `json:"duplicates,omitempty"`
+	// CoordinateMismatches is the number of records whose coordinates are outside of the country.
+	CoordinateMismatches int64 `json:"coordinate_mismatches,omitempty"`
+	// Workers is the number of goroutines writing locations, the final pool size in the adaptive mode.
+	Workers int `json:"workers,omitempty"`
+}
+
+// BoundingBox represents a rectangular area limited by latitude and longitude.
+type BoundingBox struct {
+	MinLatitude  float64
+	MaxLatitude  float64
+	MinLongitude float64
+	MaxLongitude float64
+}
+
+// Circle represents an area within a radius (in kilometers) around a point.
+type Circle struct {
+	Latitude  float64
+	Longitude float64
+	RadiusKm  float64
+}
+
+// LocationFilter criteria for searching locations.
+type LocationFilter struct {
+	CountryCode string
+	Country     string
+	City        string
+	BoundingBox *BoundingBox
+	Circle      *Circle
+	// MinQuality is the minimum quality score of the locations.
+	MinQuality int
+	Cursor     string
+	Limit      int
+}
+
+// LocationPage represents one page of the locations search result.
+// swagger:model
+type LocationPage struct {
+	Items      []Location `json:"items"`
+	NextCursor string     `json:"next_cursor,omitempty"`
+}
+
+// LocationStatistics represents aggregated data of locations in a country or city.
+// swagger:model
+type LocationStatistics struct {
+	CountryCode string  `json:"country_code"`
+	Country     string  `json:"country"`
+	City        string  `json:"city,omitempty"`
+	Count       int64   `json:"count"`
+	Latitude    float64 `json:"latitude"`
+	Longitude   float64 `json:"longitude"`
+	// MysteryValue is omitted when the field is hidden by the response fields of the service.
+	MysteryValue *MysteryValueStatistics `json:"mystery_value,omitempty"`
+}
+
+// MysteryValueStatistics represents aggregates of the mystery value.
+// swagger:model
+type MysteryValueStatistics struct {
+	Min int64   `json:"min"`
+	Max int64   `json:"max"`
+	Avg float64 `json:"avg"`
+	Sum int64   `json:"sum"`
+}
+
+// NearbyLocation represents location with the distance to the requested point.
+// swagger:model
+type NearbyLocation struct {
+	Location
+	DistanceKm float64 `json:"distance_km"`
+}
+
+// ImportJob represents an import of location files started by the admin API.
+// swagger:model
+type ImportJob struct {
+	ID     int64  `json:"id"`
+	Source string `json:"source"`
+	// Status is one of: running, completed, failed, cancelled.
+	Status     string          `json:"status"`
+	Progress   ImportProgress  `json:"progress"`
+	Statistics *LoadStatistics `json:"statistics,omitempty"`
+	Error      string          `json:"error,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+	FinishedAt *time.Time      `json:"finished_at,omitempty"`
+}
+
+// ImportProgress represents the number of processed rows of an import.
+// swagger:model
+type ImportProgress struct {
+	// Phase is one of: reading, writing, done.
+	Phase      string `json:"phase"`
+	FilesTotal int64  `json:"files_total"`
+	FilesRead  int64  `json:"files_read"`
+	BytesTotal int64  `json:"bytes_total"`
+	BytesRead  int64  `json:"bytes_read"`
+	// File is the file being read (the last one read from when files are read concurrently),
+	// FileBytesRead is the number of its bytes read.
+	File          string `json:"file,omitempty"`
+	FileSize      int64  `json:"file_size"`
+	FileBytesRead int64  `json:"file_bytes_read"`
+	RowsRead      int64  `json:"rows_read"`
+	RowsWritten   int64  `json:"rows_written"`
+	RowsToWrite   int64  `json:"rows_to_write"`
+	Retries       int64  `json:"retries"`
+	RowsFailed    int64  `json:"rows_failed"`
 }
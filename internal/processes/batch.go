@@ -0,0 +1,227 @@
+package processes
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"vio/internal/models"
+)
+
+// SQLInsertBatch upserts the locations passed as arrays of the columns.
+// Quality flags are passed comma-separated, PostgreSQL arrays of arrays must have the same length.
+var SQLInsertBatch = sqlInsertBatch + sqlUpsertLocation
+
+// SQLInsertBatchFirst inserts the locations of the IP addresses that are not stored yet.
+var SQLInsertBatchFirst = sqlInsertBatch + sqlKeepLocation
+
+var sqlInsertBatch = `INSERT INTO location (ip_address, country_code, country, city, latitude, longitude, mystery_value, country_original, city_original,
+	quality_score, quality_flags)
+SELECT ip_address, country_code, country, city, latitude, longitude, mystery_value, country_original, city_original,
+	quality_score, string_to_array(quality_flags, ',')
+FROM unnest($1::varchar[], $2::varchar[], $3::varchar[], $4::varchar[], $5::double precision[], $6::double precision[], $7::bigint[],
+	$8::text[], $9::text[], $10::smallint[], $11::text[])
+	AS batch (ip_address, country_code, country, city, latitude, longitude, mystery_value, country_original, city_original,
+	quality_score, quality_flags)
+`
+
+// locationWriter writes the locations of one goroutine.
+type locationWriter interface {
+	write(ctx context.Context, loc models.Location) error
+	// flush writes the buffered locations.
+	flush(ctx context.Context) error
+}
+
+// writeStatement creates the writers of the goroutines: locations are written one by one
+// with the prepared statement when the batch size is 1 or less, otherwise in batches.
+// With DuplicatesFirst the locations already stored, also by previous imports, are kept.
+type writeStatement struct {
+	db         *sql.DB
+	stmt       *sql.Stmt
+	batchSize  int
+	batchQuery string
+	progress   *Progress
+}
+
+func prepareWrite(db *sql.DB, batchSize int, duplicates DuplicatePolicy, progress *Progress) (*writeStatement, error) {
+	query, batchQuery := SQLInsert, SQLInsertBatch
+	if duplicates == DuplicatesFirst {
+		query, batchQuery = SQLInsertFirst, SQLInsertBatchFirst
+	}
+	w := &writeStatement{
+		db:         db,
+		batchSize:  batchSize,
+		batchQuery: batchQuery,
+		progress:   progress,
+	}
+	if batchSize > 1 {
+		return w, nil
+	}
+
+	stmt, err := db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	w.stmt = stmt
+
+	return w, nil
+}
+
+func (w *writeStatement) newWriter() locationWriter {
+	if w.stmt != nil {
+		return &rowWriter{stmt: w.stmt, progress: w.progress}
+	}
+
+	return &batchWriter{
+		db:       w.db,
+		query:    w.batchQuery,
+		batch:    make([]models.Location, 0, w.batchSize),
+		progress: w.progress,
+	}
+}
+
+func (w *writeStatement) Close() error {
+	if w.stmt == nil {
+		return nil
+	}
+
+	return w.stmt.Close()
+}
+
+type rowWriter struct {
+	stmt     *sql.Stmt
+	progress *Progress
+}
+
+func (w *rowWriter) write(ctx context.Context, loc models.Location) error {
+	return insertLocation(ctx, w.stmt, loc, w.progress)
+}
+
+func (w *rowWriter) flush(context.Context) error {
+	return nil
+}
+
+// batchWriter collects the locations and writes them when the batch is full.
+type batchWriter struct {
+	db       *sql.DB
+	query    string
+	batch    []models.Location
+	progress *Progress
+}
+
+func (w *batchWriter) write(ctx context.Context, loc models.Location) error {
+	w.batch = append(w.batch, loc)
+	if len(w.batch) < cap(w.batch) {
+		return nil
+	}
+
+	return w.flush(ctx)
+}
+
+func (w *batchWriter) flush(ctx context.Context) error {
+	if len(w.batch) == 0 {
+		return nil
+	}
+	err := insertBatch(ctx, w.db, w.query, w.batch, w.progress)
+	w.batch = w.batch[:0]
+
+	return err
+}
+
+// insertBatch writes the locations with one statement of the query in a transaction, retrying transient errors.
+// When the batch fails with a permanent error, the locations are written one by one, so only the bad ones fail.
+// Retries and failed locations are counted in the progress.
+// The locations of the batch must have different IP addresses.
+func insertBatch(ctx context.Context, db *sql.DB, query string, locations []models.Location, progress *Progress) error {
+	err := withRetry(ctx, progress, func() error {
+		return execBatch(ctx, db, query, locations)
+	})
+	switch {
+	case err == nil:
+		progress.batchWritten(len(locations))
+	case ctx.Err() != nil:
+	case len(locations) > 1 && !IsRetryable(err):
+		return insertRows(ctx, db, query, locations, progress)
+	default:
+		progress.batchFailed(len(locations))
+	}
+
+	return err
+}
+
+// insertRows writes every location as a batch of one, the errors of the failed locations are joined.
+func insertRows(ctx context.Context, db *sql.DB, query string, locations []models.Location, progress *Progress) error {
+	var errs []error
+	for i := range locations {
+		if ctx.Err() != nil {
+			break
+		}
+		err := withRetry(ctx, progress, func() error {
+			return execBatch(ctx, db, query, locations[i:i+1])
+		})
+		switch {
+		case err == nil:
+			progress.rowWritten()
+		case ctx.Err() == nil:
+			progress.rowFailed()
+			errs = append(errs, fmt.Errorf("IP address %s: %w", locations[i].IPAddress, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func execBatch(ctx context.Context, db *sql.DB, query string, locations []models.Location) error {
+	ipAddresses := make([]string, len(locations))
+	countryCodes := make([]string, len(locations))
+	countries := make([]string, len(locations))
+	cities := make([]string, len(locations))
+	latitudes := make([]float64, len(locations))
+	longitudes := make([]float64, len(locations))
+	mysteryValues := make([]int64, len(locations))
+	countriesOriginal := make([]sql.NullString, len(locations))
+	citiesOriginal := make([]sql.NullString, len(locations))
+	qualityScores := make([]int64, len(locations))
+	qualityFlags := make([]string, len(locations))
+	for i, loc := range locations {
+		ipAddresses[i] = loc.IPAddress
+		countryCodes[i] = loc.CountryCode
+		countries[i] = loc.Country
+		cities[i] = loc.City
+		latitudes[i] = loc.Latitude
+		longitudes[i] = loc.Longitude
+		mysteryValues[i] = loc.MysteryValue
+		countriesOriginal[i] = nullIfEmpty(loc.CountryOriginal)
+		citiesOriginal[i] = nullIfEmpty(loc.CityOriginal)
+		qualityScores[i] = int64(loc.QualityScore)
+		qualityFlags[i] = strings.Join(loc.QualityFlags, ",")
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, query,
+		pq.Array(ipAddresses),
+		pq.Array(countryCodes),
+		pq.Array(countries),
+		pq.Array(cities),
+		pq.Array(latitudes),
+		pq.Array(longitudes),
+		pq.Array(mysteryValues),
+		pq.Array(countriesOriginal),
+		pq.Array(citiesOriginal),
+		pq.Array(qualityScores),
+		pq.Array(qualityFlags),
+	)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
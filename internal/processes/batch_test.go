@@ -0,0 +1,231 @@
+package processes
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vio/internal/models"
+	"vio/internal/testhelpers"
+)
+
+func TestProcessBatches(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	locations := []models.Location{
+		{IPAddress: "70.95.73.73", CountryCode: "TL", Country: "Saudi Arabia", City: "Gradymouth", Latitude: -49.5, Longitude: -86.5, MysteryValue: 2559997162, QualityScore: 100},
+		{IPAddress: "125.159.20.54", CountryCode: "LI", Country: "Guyana", City: "Port Karson", Latitude: -78.25, Longitude: -163.25, MysteryValue: 1337885276, CityOriginal: "port karson", QualityScore: 70, QualityFlags: []string{"country_mismatch"}},
+		{IPAddress: "1.1.1.1", QualityFlags: []string{"missing_country", "missing_city", "zero_coordinates"}},
+	}
+
+	expectedSQL := regexp.QuoteMeta(SQLInsertBatch)
+	mock.ExpectBegin()
+	mock.ExpectExec(expectedSQL).WithArgs(
+		pq.Array([]string{"70.95.73.73", "125.159.20.54"}),
+		pq.Array([]string{"TL", "LI"}),
+		pq.Array([]string{"Saudi Arabia", "Guyana"}),
+		pq.Array([]string{"Gradymouth", "Port Karson"}),
+		pq.Array([]float64{-49.5, -78.25}),
+		pq.Array([]float64{-86.5, -163.25}),
+		pq.Array([]int64{2559997162, 1337885276}),
+		pq.Array([]sql.NullString{{}, {}}),
+		pq.Array([]sql.NullString{{}, {String: "port karson", Valid: true}}),
+		pq.Array([]int64{100, 70}),
+		pq.Array([]string{"", "country_mismatch"}),
+	).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec(expectedSQL).WithArgs(
+		pq.Array([]string{"1.1.1.1"}),
+		pq.Array([]string{""}),
+		pq.Array([]string{""}),
+		pq.Array([]string{""}),
+		pq.Array([]float64{0}),
+		pq.Array([]float64{0}),
+		pq.Array([]int64{0}),
+		pq.Array([]sql.NullString{{}}),
+		pq.Array([]sql.NullString{{}}),
+		pq.Array([]int64{0}),
+		pq.Array([]string{"missing_country,missing_city,zero_coordinates"}),
+	).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	progress := &Progress{}
+	resultTime, err := process(context.Background(), db, &locations, 2, DuplicatesLast, progress)
+	require.NoError(t, err)
+	assert.True(t, len(resultTime) > 0, "unexpected finish time")
+	assert.Equal(t, int64(3), progress.Snapshot().RowsWritten)
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func Test_insertBatch(t *testing.T) {
+	retry := Retry
+	Retry = RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond}
+	t.Cleanup(func() { Retry = retry })
+
+	tests := []struct {
+		name        string
+		errs        []error
+		wantErr     string
+		wantWritten int64
+		wantRetries int64
+		wantFailed  int64
+	}{
+		{
+			name:        "Written",
+			errs:        []error{nil},
+			wantWritten: 2,
+		},
+		{
+			name:        "Retried after deadlock",
+			errs:        []error{&pq.Error{Code: "40P01"}, nil},
+			wantWritten: 2,
+			wantRetries: 1,
+		},
+		{
+			name:        "Permanent error falls back to rows",
+			errs:        []error{&pq.Error{Code: "22001"}, nil, &pq.Error{Code: "22001"}},
+			wantErr:     "IP address 1.1.1.1",
+			wantWritten: 1,
+			wantFailed:  1,
+		},
+		{
+			name:        "Exhausted retries fail the batch",
+			errs:        []error{&pq.Error{Code: "40P01"}, &pq.Error{Code: "40P01"}},
+			wantErr:     "pq: ",
+			wantRetries: 1,
+			wantFailed:  2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer db.Close()
+
+			for _, errExec := range tt.errs {
+				mock.ExpectBegin()
+				exec := mock.ExpectExec(regexp.QuoteMeta(SQLInsertBatch))
+				if errExec != nil {
+					exec.WillReturnError(errExec)
+					mock.ExpectRollback()
+				} else {
+					exec.WillReturnResult(sqlmock.NewResult(0, 2))
+					mock.ExpectCommit()
+				}
+			}
+
+			progress := &Progress{}
+			locations := []models.Location{{IPAddress: "70.95.73.73"}, {IPAddress: "1.1.1.1"}}
+			err = insertBatch(context.Background(), db, SQLInsertBatch, locations, progress)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			got := progress.Snapshot()
+			assert.Equal(t, tt.wantWritten, got.RowsWritten)
+			assert.Equal(t, tt.wantRetries, got.Retries)
+			assert.Equal(t, tt.wantFailed, got.RowsFailed)
+
+			if err = mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
+
+func Test_prepareWrite_duplicates(t *testing.T) {
+	tests := []struct {
+		name       string
+		batchSize  int
+		duplicates DuplicatePolicy
+		wantSQL    string
+	}{
+		{name: "Last replaces stored rows", batchSize: 1, duplicates: DuplicatesLast, wantSQL: SQLInsert},
+		{name: "First keeps stored rows", batchSize: 1, duplicates: DuplicatesFirst, wantSQL: SQLInsertFirst},
+		{name: "Last replaces stored batches", batchSize: 2, duplicates: DuplicatesLast, wantSQL: SQLInsertBatch},
+		{name: "First keeps stored batches", batchSize: 2, duplicates: DuplicatesFirst, wantSQL: SQLInsertBatchFirst},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer db.Close()
+
+			if tt.batchSize > 1 {
+				mock.ExpectBegin()
+				mock.ExpectExec(tt.wantSQL).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			} else {
+				mock.ExpectPrepare(tt.wantSQL).ExpectExec().WillReturnResult(sqlmock.NewResult(0, 1))
+			}
+
+			locations := []models.Location{{IPAddress: "70.95.73.73"}}
+			_, err = process(context.Background(), db, &locations, tt.batchSize, tt.duplicates, nil)
+			require.NoError(t, err)
+
+			if err = mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
+
+// BenchmarkProcessParallel compares writing locations one by one with batches on a PostgreSQL container:
+//
+//	go test -run '^$' -bench ProcessParallel ./internal/processes
+func BenchmarkProcessParallel(b *testing.B) {
+	testDB, err := testhelpers.NewTestDatabase(b)
+	if err != nil {
+		b.Skip("Error connecting to the database: ", err)
+	}
+	defer testDB.Close(b)
+
+	locations := make([]models.Location, 10000)
+	for i := range locations {
+		locations[i] = models.Location{
+			IPAddress:    fmt.Sprintf("10.%d.%d.%d", i>>16&255, i>>8&255, i&255),
+			CountryCode:  "NL",
+			Country:      "Netherlands",
+			City:         "Amsterdam",
+			Latitude:     52.37,
+			Longitude:    4.89,
+			MysteryValue: int64(i),
+		}
+	}
+
+	for _, batchSize := range []int{1, 100, 1000} {
+		batchSize := batchSize
+		b.Run(fmt.Sprintf("batch_size=%d", batchSize), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_, err := processParallelWithMaxProcs(context.Background(), testDB.DB(), &locations, 8, batchSize, DuplicatesLast, nil)
+				require.NoError(b, err)
+			}
+			b.ReportMetric(float64(len(locations)*b.N)/b.Elapsed().Seconds(), "rows/s")
+		})
+	}
+}
@@ -0,0 +1,271 @@
+package processes
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+
+	"vio/internal/models"
+)
+
+// minChunkSize is the smallest part of a file parsed by a separate goroutine.
+var minChunkSize int64 = 8 << 20
+
+// sourceFile is a source file split into chunks of whole records.
+type sourceFile struct {
+	path string
+	name string
+	size int64
+	// fields is the number of fields of the header, records of all chunks must have it.
+	fields int
+	// columns are the indexes of the location fields in the records of chunks after the first one.
+	columns []int
+	chunks  []chunk
+	// err is the error opening the file.
+	err error
+
+	// read is the number of bytes of the file parsed, pending is the number of chunks not parsed yet.
+	read    atomic.Int64
+	pending atomic.Int64
+}
+
+// chunk is the byte range of the file from start to end.
+type chunk struct {
+	start int64
+	end   int64
+	// line is the number of lines of the file before the chunk.
+	line int
+}
+
+type chunkResult struct {
+	// started is false when the context is cancelled before the chunk is parsed.
+	started   bool
+	locations []models.Location
+	// statistics are the discarded records and the counters of the validation.
+	statistics models.LoadStatistics
+	// headerErr is the error reading the header of the file, err is the error parsing a record.
+	headerErr error
+	err       error
+}
+
+// planSourceFiles splits the files into chunks, a file is split into as many chunks as workers
+// but not smaller than minChunkSize.
+func planSourceFiles(filePaths []string, workers int, opts ImportOptions) []*sourceFile {
+	files := make([]*sourceFile, 0, len(filePaths))
+	for _, filePath := range filePaths {
+		f := &sourceFile{
+			path: filePath,
+			name: filepath.Base(filePath),
+		}
+		files = append(files, f)
+
+		file, err := os.Open(filePath)
+		if err != nil {
+			f.err = fmt.Errorf("error opening file %s: %v", f.name, err)
+			continue
+		}
+		if info, errStat := file.Stat(); errStat == nil {
+			f.size = info.Size()
+		}
+		f.chunks = []chunk{{end: f.size}}
+		if n := int(min(int64(workers), f.size/minChunkSize)); n > 1 {
+			// The file is read as a whole when the header is invalid, so the first chunk reports the error.
+			if header, chunks, errSplit := splitFile(file, f.size, n, opts.Format); errSplit == nil {
+				if columns, errColumns := opts.Columns.indexes(header); errColumns == nil {
+					f.fields = len(header)
+					f.columns = columns
+					f.chunks = chunks
+				}
+			}
+		}
+		f.pending.Store(int64(len(f.chunks)))
+		_ = file.Close()
+	}
+
+	return files
+}
+
+// splitFile reads the header and splits the file into n chunks of about the same size.
+// Chunks end with a line break outside of quoted fields, so they contain whole records.
+func splitFile(file *os.File, size int64, n int, format SourceFormat) ([]string, []chunk, error) {
+	header, err := format.newReader(io.NewSectionReader(file, 0, size), 0).Read()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	chunks := make([]chunk, 0, n)
+	current := chunk{}
+	target := size / int64(n)
+	quoted := false
+	line := 0
+	separators := "\n"
+	if format.quoted() {
+		separators = "\"\n"
+	}
+	buf := make([]byte, 1<<20)
+	var offset int64
+	for len(chunks) < n-1 {
+		read, err := file.ReadAt(buf, offset)
+		block := buf[:read]
+		for i := 0; ; {
+			j := bytes.IndexAny(block[i:], separators)
+			if j < 0 {
+				break
+			}
+			i += j
+			if block[i] == '"' {
+				// An escaped quote toggles the state twice.
+				quoted = !quoted
+			} else {
+				line++
+				if end := offset + int64(i) + 1; !quoted && end >= target && end < size {
+					current.end = end
+					chunks = append(chunks, current)
+					current = chunk{start: end, line: line}
+					target = end + (size-end)/int64(n-len(chunks))
+					if len(chunks) == n-1 {
+						break
+					}
+				}
+			}
+			i++
+		}
+		offset += int64(read)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, nil, err
+		}
+	}
+	current.end = size
+
+	return header, append(chunks, current), nil
+}
+
+// parseChunks parses the chunks of the files with the number of goroutines.
+// Results are returned in the order of files and their chunks.
+func parseChunks(ctx context.Context, files []*sourceFile, workers int, opts ImportOptions, progress *Progress) []chunkResult {
+	type task struct {
+		file  *sourceFile
+		chunk chunk
+	}
+	var tasks []task
+	for _, f := range files {
+		for _, ch := range f.chunks {
+			tasks = append(tasks, task{file: f, chunk: ch})
+		}
+	}
+	results := make([]chunkResult, len(tasks))
+
+	var wg sync.WaitGroup
+	queue := make(chan int)
+	for i := 0; i < min(workers, len(tasks)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range queue {
+				f := tasks[i].file
+				results[i] = parseChunk(ctx, f, tasks[i].chunk, opts, progress)
+				if f.pending.Add(-1) == 0 {
+					progress.fileParsed(f.name, f.size, f.size-f.read.Load())
+				}
+			}
+		}()
+	}
+
+	for i := range tasks {
+		if ctx.Err() != nil {
+			break
+		}
+		queue <- i
+	}
+	close(queue)
+	wg.Wait()
+
+	return results
+}
+
+func parseChunk(ctx context.Context, f *sourceFile, ch chunk, opts ImportOptions, progress *Progress) chunkResult {
+	result := chunkResult{started: true}
+	file, err := os.Open(f.path)
+	if err != nil {
+		result.err = fmt.Errorf("error opening file %s: %v", f.name, err)
+		return result
+	}
+	defer file.Close()
+
+	// Records of the first chunk must have the fields of its header.
+	fields := f.fields
+	if ch.start == 0 {
+		fields = 0
+	}
+	reader := opts.Format.newReader(io.NewSectionReader(file, ch.start, ch.end-ch.start), fields)
+	columns := f.columns
+	if ch.start == 0 {
+		// The header of the file locates the columns of the location fields.
+		header, err := reader.Read()
+		if err != nil {
+			result.headerErr = err
+			return result
+		}
+		columns, err = opts.Columns.indexes(header)
+		if err != nil {
+			result.headerErr = fmt.Errorf("invalid header of file %s: %w", f.name, err)
+			return result
+		}
+	}
+
+	done := ctx.Done()
+	var offset int64
+	for {
+		select {
+		case <-done:
+			return result
+		default:
+		}
+
+		record, err := reader.Read()
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				result.err = fileLineError(err, ch.line)
+			}
+
+			return result
+		}
+
+		location := parseRecord(record, columns)
+
+		progress.rowRead()
+		n := reader.InputOffset() - offset
+		offset += n
+		progress.chunkRead(f.name, f.size, f.read.Add(n), n)
+
+		if !opts.Validation.validate(&location, &result.statistics) {
+			result.statistics.Discarded++
+			continue
+		}
+
+		result.locations = append(result.locations, location)
+	}
+}
+
+// fileLineError converts lines of the parse error in the chunk to the lines of the file.
+func fileLineError(err error, line int) error {
+	var parseErr *csv.ParseError
+	if line == 0 || !errors.As(err, &parseErr) {
+		return err
+	}
+	fileErr := *parseErr
+	fileErr.StartLine += line
+	fileErr.Line += line
+
+	return &fileErr
+}
@@ -0,0 +1,115 @@
+package processes
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"vio/internal/models"
+)
+
+// CoordinatePolicy is the handling of records whose coordinates are outside of the country.
+type CoordinatePolicy string
+
+const (
+	// CoordinatesOff does not count and reject records outside of the country,
+	// they get the outside_country quality flag anyway.
+	CoordinatesOff CoordinatePolicy = "off"
+	// CoordinatesFlag imports records outside of the country counting them in the statistics.
+	CoordinatesFlag CoordinatePolicy = "flag"
+	// CoordinatesReject discards records outside of the country.
+	CoordinatesReject CoordinatePolicy = "reject"
+)
+
+// ParseCoordinatePolicy parses the policy, an empty value is CoordinatesOff.
+func ParseCoordinatePolicy(value string) (CoordinatePolicy, error) {
+	switch policy := CoordinatePolicy(value); policy {
+	case "":
+		return CoordinatesOff, nil
+	case CoordinatesOff, CoordinatesFlag, CoordinatesReject:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("invalid coordinate policy %q: must be off, flag or reject", value)
+	}
+}
+
+// countryBoxMargin extends the country boxes in degrees (about 55 km),
+// so points near the border and the coast are inside.
+const countryBoxMargin = 0.5
+
+//go:embed country_boxes.csv
+var countryBoxesCSV string
+
+var countryBoxes = mustParseCountryBoxes(countryBoxesCSV)
+
+func mustParseCountryBoxes(data string) map[string]models.BoundingBox {
+	reader := csv.NewReader(strings.NewReader(data))
+	reader.Comment = '#'
+	records, err := reader.ReadAll()
+	if err != nil {
+		panic(fmt.Sprintf("invalid country boxes: %s", err))
+	}
+
+	boxes := make(map[string]models.BoundingBox, len(records))
+	// The first record is the header.
+	for _, record := range records[1:] {
+		var values [4]float64
+		for i := range values {
+			values[i], err = strconv.ParseFloat(record[i+1], 64)
+			if err != nil {
+				panic(fmt.Sprintf("invalid box of %s: %s", record[0], err))
+			}
+		}
+		boxes[record[0]] = models.BoundingBox{
+			MinLatitude:  values[0],
+			MaxLatitude:  values[1],
+			MinLongitude: values[2],
+			MaxLongitude: values[3],
+		}
+	}
+
+	return boxes
+}
+
+// outsideCountry reports whether the coordinates of the location are outside of the box of its country
+// extended by countryBoxMargin. Locations of unknown countries and without coordinates are not outside.
+func outsideCountry(location *models.Location) bool {
+	box, ok := countryBoxes[strings.ToUpper(location.CountryCode)]
+	if !ok || (location.Latitude == 0 && location.Longitude == 0) {
+		return false
+	}
+
+	return !insideBox(box, location.Latitude, location.Longitude, countryBoxMargin)
+}
+
+// insideBox reports whether the point is inside the box extended by the margin in degrees,
+// the box crosses the antimeridian when the minimum longitude is greater than the maximum longitude.
+func insideBox(box models.BoundingBox, latitude, longitude, margin float64) bool {
+	if latitude < box.MinLatitude-margin || latitude > box.MaxLatitude+margin {
+		return false
+	}
+
+	// Longitudes are compared as eastward offsets from the western edge of the box.
+	width := box.MaxLongitude - box.MinLongitude
+	if width < 0 {
+		width += 360
+	}
+	width += 2 * margin
+	offset := math.Mod(longitude-(box.MinLongitude-margin)+720, 360)
+
+	return width >= 360 || offset <= width
+}
+
+// checkCoordinates counts and rejects the location outside of its country by the policy.
+// It returns false when the location must be discarded.
+func (p CoordinatePolicy) checkCoordinates(location *models.Location, statistics *models.LoadStatistics) bool {
+	if p == "" || p == CoordinatesOff || !outsideCountry(location) {
+		return true
+	}
+	statistics.CoordinateMismatches++
+
+	return p != CoordinatesReject
+}
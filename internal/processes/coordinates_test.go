@@ -0,0 +1,134 @@
+package processes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"vio/internal/models"
+)
+
+func TestCoordinatePolicy_checkCoordinates(t *testing.T) {
+	tests := []struct {
+		name           string
+		policy         CoordinatePolicy
+		location       models.Location
+		want           bool
+		wantMismatches int64
+	}{
+		{
+			name:     "Inside of the country",
+			policy:   CoordinatesReject,
+			location: models.Location{CountryCode: "NL", Latitude: 52.37, Longitude: 4.89},
+			want:     true,
+		},
+		{
+			name:     "Near the border",
+			policy:   CoordinatesReject,
+			location: models.Location{CountryCode: "NL", Latitude: 53.7, Longitude: 7.4},
+			want:     true,
+		},
+		{
+			name:     "Across the antimeridian",
+			policy:   CoordinatesReject,
+			location: models.Location{CountryCode: "ru", Latitude: 66.1, Longitude: -175},
+			want:     true,
+		},
+		{
+			name:     "Not checked by default",
+			location: models.Location{CountryCode: "SI", Latitude: -84.87, Longitude: 7.2},
+			want:     true,
+		},
+		{
+			name:           "Outside is flagged",
+			policy:         CoordinatesFlag,
+			location:       models.Location{CountryCode: "SI", Latitude: -84.87, Longitude: 7.2},
+			want:           true,
+			wantMismatches: 1,
+		},
+		{
+			name:           "Outside is rejected",
+			policy:         CoordinatesReject,
+			location:       models.Location{CountryCode: "NZ", Latitude: -41.29, Longitude: 4.5},
+			want:           false,
+			wantMismatches: 1,
+		},
+		{
+			name:     "Unknown country is not checked",
+			policy:   CoordinatesReject,
+			location: models.Location{CountryCode: "XX", Latitude: -84.87, Longitude: 7.2},
+			want:     true,
+		},
+		{
+			name:     "Zero coordinates are not checked",
+			policy:   CoordinatesReject,
+			location: models.Location{CountryCode: "SI"},
+			want:     true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var statistics models.LoadStatistics
+			assert.Equal(t, tt.want, tt.policy.checkCoordinates(&tt.location, &statistics))
+			assert.Equal(t, tt.wantMismatches, statistics.CoordinateMismatches)
+		})
+	}
+}
+
+func Test_insideBox(t *testing.T) {
+	box := models.BoundingBox{MinLatitude: 10, MaxLatitude: 20, MinLongitude: 30, MaxLongitude: 40}
+	crossing := models.BoundingBox{MinLatitude: -50, MaxLatitude: -30, MinLongitude: 165, MaxLongitude: -175}
+	world := models.BoundingBox{MinLatitude: -90, MaxLatitude: -60, MinLongitude: -180, MaxLongitude: 180}
+	tests := []struct {
+		name      string
+		box       models.BoundingBox
+		latitude  float64
+		longitude float64
+		want      bool
+	}{
+		{name: "Inside", box: box, latitude: 15, longitude: 35, want: true},
+		{name: "Inside the margin", box: box, latitude: 20.4, longitude: 29.6, want: true},
+		{name: "North", box: box, latitude: 20.6, longitude: 35},
+		{name: "West", box: box, latitude: 15, longitude: 29.4},
+		{name: "East", box: box, latitude: 15, longitude: 40.6},
+		{name: "Crossing west of the antimeridian", box: crossing, latitude: -40, longitude: 175, want: true},
+		{name: "Crossing east of the antimeridian", box: crossing, latitude: -40, longitude: -176, want: true},
+		{name: "Crossing outside", box: crossing, latitude: -40, longitude: 0},
+		{name: "Whole longitude range", box: world, latitude: -70, longitude: 0, want: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.want, insideBox(tt.box, tt.latitude, tt.longitude, countryBoxMargin))
+		})
+	}
+}
+
+func TestParseCoordinatePolicy(t *testing.T) {
+	for _, value := range []string{"off", "flag", "reject"} {
+		got, err := ParseCoordinatePolicy(value)
+		assert.NoError(t, err)
+		assert.Equal(t, CoordinatePolicy(value), got)
+	}
+	got, err := ParseCoordinatePolicy("")
+	assert.NoError(t, err)
+	assert.Equal(t, CoordinatesOff, got)
+
+	_, err = ParseCoordinatePolicy("correct")
+	assert.Error(t, err)
+}
+
+func Test_countryBoxes(t *testing.T) {
+	assert.Len(t, countryBoxes, len(countries.byCode))
+	for code := range countries.byCode {
+		box, ok := countryBoxes[code]
+		if assert.Truef(t, ok, "box of %s", code) {
+			assert.LessOrEqualf(t, box.MinLatitude, box.MaxLatitude, "latitudes of %s", code)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package processes
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+
+	"vio/internal/models"
+)
+
+// CountryPolicy is the handling of records whose country code and country name disagree.
+type CountryPolicy string
+
+const (
+	// CountriesOff does not check countries.
+	CountriesOff CountryPolicy = "off"
+	// CountriesFlag imports mismatched records counting them in the statistics.
+	CountriesFlag CountryPolicy = "flag"
+	// CountriesCorrect replaces the country name by the name of the country code,
+	// or the unknown country code by the code of the country name.
+	CountriesCorrect CountryPolicy = "correct"
+	// CountriesReject discards mismatched records.
+	CountriesReject CountryPolicy = "reject"
+)
+
+// ParseCountryPolicy parses the policy, an empty value is CountriesOff.
+func ParseCountryPolicy(value string) (CountryPolicy, error) {
+	switch policy := CountryPolicy(value); policy {
+	case "":
+		return CountriesOff, nil
+	case CountriesOff, CountriesFlag, CountriesCorrect, CountriesReject:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("invalid country policy %q: must be off, flag, correct or reject", value)
+	}
+}
+
+//go:embed iso3166.csv
+var iso3166CSV string
+
+// country of ISO 3166-1.
+type country struct {
+	code   string
+	alpha3 string
+	name   string
+}
+
+// countryTable looks up countries by the alpha-2 code and by the normalized names.
+type countryTable struct {
+	byCode map[string]*country
+	byName map[string]*country
+}
+
+var countries = mustParseCountries(iso3166CSV)
+
+func mustParseCountries(data string) *countryTable {
+	reader := csv.NewReader(strings.NewReader(data))
+	reader.Comment = '#'
+	records, err := reader.ReadAll()
+	if err != nil {
+		panic(fmt.Sprintf("invalid ISO 3166 table: %s", err))
+	}
+
+	table := &countryTable{byCode: make(map[string]*country), byName: make(map[string]*country)}
+	// The first record is the header.
+	for _, record := range records[1:] {
+		c := &country{code: record[0], alpha3: record[1], name: record[2]}
+		table.byCode[c.code] = c
+		table.byName[countryNameKey(c.name)] = c
+		if record[3] == "" {
+			continue
+		}
+		for _, name := range strings.Split(record[3], "|") {
+			table.byName[countryNameKey(name)] = c
+		}
+	}
+
+	return table
+}
+
+// countryNameKey is the form names are compared in: lower case without diacritics and punctuation,
+// "&" is the same as "and" and "St." is the same as "Saint".
+func countryNameKey(name string) string {
+	name, _, _ = transform.String(transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC), name)
+	name = strings.ReplaceAll(strings.ToLower(name), "&", " and ")
+
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\'' && r != '’'
+	})
+	for i, word := range words {
+		word = strings.NewReplacer("'", "", "’", "").Replace(word)
+		if word == "st" {
+			word = "saint"
+		}
+		words[i] = word
+	}
+
+	return strings.Join(words, " ")
+}
+
+// mismatch reports whether the country code and the country name are not the same known country.
+func (t *countryTable) mismatch(code, name string) bool {
+	byCode, codeOK := t.byCode[strings.ToUpper(code)]
+	byName, nameOK := t.byName[countryNameKey(name)]
+
+	return !codeOK || !nameOK || byCode != byName
+}
+
+// checkCountry checks that the country code and the country name of the location are the same country,
+// records with an empty code or name are not checked. Mismatches and corrections are counted
+// in the statistics. It returns false when the location must be discarded.
+func (p CountryPolicy) checkCountry(location *models.Location, statistics *models.LoadStatistics) bool {
+	if p == "" || p == CountriesOff || location.CountryCode == "" || location.Country == "" {
+		return true
+	}
+
+	if !countries.mismatch(location.CountryCode, location.Country) {
+		return true
+	}
+	statistics.CountryMismatches++
+
+	switch p {
+	case CountriesReject:
+		return false
+	case CountriesCorrect:
+		// The code is the key of the country, a known code wins over the name.
+		byCode, codeOK := countries.byCode[strings.ToUpper(location.CountryCode)]
+		byName, nameOK := countries.byName[countryNameKey(location.Country)]
+		switch {
+		case codeOK:
+			// The source value is kept for auditing unless the normalization already kept it.
+			if location.CountryOriginal == "" {
+				location.CountryOriginal = location.Country
+			}
+			location.Country = byCode.name
+		case nameOK:
+			location.CountryCode = byName.code
+		default:
+			return true
+		}
+		location.QualityFlags = append(location.QualityFlags, FlagCountryCorrected)
+		statistics.CountryCorrections++
+	}
+
+	return true
+}
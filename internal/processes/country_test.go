@@ -0,0 +1,173 @@
+package processes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"vio/internal/models"
+)
+
+func TestCountryPolicy_checkCountry(t *testing.T) {
+	tests := []struct {
+		name            string
+		policy          CountryPolicy
+		countryCode     string
+		country         string
+		countryOriginal string
+		want            bool
+		wantCountryCode string
+		wantCountry     string
+		wantOriginal    string
+		wantMismatches  int64
+		wantCorrections int64
+	}{
+		{
+			name:            "Consistent country",
+			policy:          CountriesReject,
+			countryCode:     "NL",
+			country:         "Netherlands",
+			want:            true,
+			wantCountryCode: "NL",
+			wantCountry:     "Netherlands",
+		},
+		{
+			name:            "Alternative name without diacritics",
+			policy:          CountriesReject,
+			countryCode:     "ci",
+			country:         "COTE D'IVOIRE",
+			want:            true,
+			wantCountryCode: "ci",
+			wantCountry:     "COTE D'IVOIRE",
+		},
+		{
+			name:            "Saint and ampersand",
+			policy:          CountriesReject,
+			countryCode:     "KN",
+			country:         "St. Kitts & Nevis",
+			want:            true,
+			wantCountryCode: "KN",
+			wantCountry:     "St. Kitts & Nevis",
+		},
+		{
+			name:            "Not checked by default",
+			countryCode:     "SI",
+			country:         "Nepal",
+			want:            true,
+			wantCountryCode: "SI",
+			wantCountry:     "Nepal",
+		},
+		{
+			name:            "Mismatch is flagged",
+			policy:          CountriesFlag,
+			countryCode:     "SI",
+			country:         "Nepal",
+			want:            true,
+			wantCountryCode: "SI",
+			wantCountry:     "Nepal",
+			wantMismatches:  1,
+		},
+		{
+			name:            "Name is corrected by the code",
+			policy:          CountriesCorrect,
+			countryCode:     "CZ",
+			country:         "Nicaragua",
+			want:            true,
+			wantCountryCode: "CZ",
+			wantCountry:     "Czechia",
+			wantOriginal:    "Nicaragua",
+			wantMismatches:  1,
+			wantCorrections: 1,
+		},
+		{
+			name:            "Normalized original name is kept",
+			policy:          CountriesCorrect,
+			countryCode:     "CZ",
+			country:         "Nicaragua",
+			countryOriginal: "NICARAGUA",
+			want:            true,
+			wantCountryCode: "CZ",
+			wantCountry:     "Czechia",
+			wantOriginal:    "NICARAGUA",
+			wantMismatches:  1,
+			wantCorrections: 1,
+		},
+		{
+			name:            "Unknown code is corrected by the name",
+			policy:          CountriesCorrect,
+			countryCode:     "XX",
+			country:         "Czech Republic",
+			want:            true,
+			wantCountryCode: "CZ",
+			wantCountry:     "Czech Republic",
+			wantMismatches:  1,
+			wantCorrections: 1,
+		},
+		{
+			name:            "Unknown code and name are not corrected",
+			policy:          CountriesCorrect,
+			countryCode:     "XX",
+			country:         "Atlantis",
+			want:            true,
+			wantCountryCode: "XX",
+			wantCountry:     "Atlantis",
+			wantMismatches:  1,
+		},
+		{
+			name:           "Mismatch is rejected",
+			policy:         CountriesReject,
+			countryCode:    "TL",
+			country:        "Saudi Arabia",
+			want:           false,
+			wantMismatches: 1,
+		},
+		{
+			name:            "Empty name is not checked",
+			policy:          CountriesReject,
+			countryCode:     "TL",
+			want:            true,
+			wantCountryCode: "TL",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var statistics models.LoadStatistics
+			location := models.Location{CountryCode: tt.countryCode, Country: tt.country, CountryOriginal: tt.countryOriginal}
+			assert.Equal(t, tt.want, tt.policy.checkCountry(&location, &statistics))
+			if tt.want {
+				assert.Equal(t, tt.wantCountryCode, location.CountryCode)
+				assert.Equal(t, tt.wantCountry, location.Country)
+				assert.Equal(t, tt.wantOriginal, location.CountryOriginal)
+			}
+			assert.Equal(t, tt.wantMismatches, statistics.CountryMismatches)
+			assert.Equal(t, tt.wantCorrections, statistics.CountryCorrections)
+		})
+	}
+}
+
+func TestParseCountryPolicy(t *testing.T) {
+	for _, value := range []string{"off", "flag", "correct", "reject"} {
+		got, err := ParseCountryPolicy(value)
+		assert.NoError(t, err)
+		assert.Equal(t, CountryPolicy(value), got)
+	}
+	got, err := ParseCountryPolicy("")
+	assert.NoError(t, err)
+	assert.Equal(t, CountriesOff, got)
+
+	_, err = ParseCountryPolicy("fix")
+	assert.Error(t, err)
+}
+
+func Test_countries(t *testing.T) {
+	assert.Len(t, countries.byCode, 249)
+	for code, c := range countries.byCode {
+		assert.Len(t, code, 2)
+		assert.Lenf(t, c.alpha3, 3, "alpha-3 code of %s", code)
+		// Every name is found by its own key.
+		assert.Samef(t, c, countries.byName[countryNameKey(c.name)], "name of %s", code)
+	}
+}
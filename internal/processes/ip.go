@@ -0,0 +1,112 @@
+package processes
+
+import (
+	_ "embed"
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// AddressClass is the kind of the IP address by the special-purpose address registries (RFC 6890).
+type AddressClass string
+
+const (
+	ClassPublic      AddressClass = "public"
+	ClassPrivate     AddressClass = "private"
+	ClassLoopback    AddressClass = "loopback"
+	ClassLinkLocal   AddressClass = "link_local"
+	ClassMulticast   AddressClass = "multicast"
+	ClassUnspecified AddressClass = "unspecified"
+	// ClassDocumentation addresses are reserved for examples (RFC 5737, RFC 3849, RFC 9637).
+	ClassDocumentation AddressClass = "documentation"
+	// ClassReserved addresses are reserved for other special purposes: benchmarking, protocol assignments,
+	// future use, broadcast, etc.
+	ClassReserved AddressClass = "reserved"
+)
+
+// specialRangesCSV is the list of special-purpose ranges, update it when the IANA registries change.
+//
+//go:embed special_ranges.csv
+var specialRangesCSV string
+
+// specialRanges are the special-purpose address ranges, more specific ranges go first.
+var specialRanges = mustParseSpecialRanges(specialRangesCSV)
+
+type specialRange struct {
+	prefix netip.Prefix
+	class  AddressClass
+}
+
+// mustParseSpecialRanges parses the lines "prefix,class,reference" skipping the header and comments.
+func mustParseSpecialRanges(data string) []specialRange {
+	var ranges []specialRange
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "prefix,") {
+			continue
+		}
+		fields := strings.Split(line, ",")
+		if len(fields) < 2 {
+			panic(fmt.Sprintf("invalid special-purpose range %q", line))
+		}
+		ranges = append(ranges, specialRange{
+			prefix: netip.MustParsePrefix(fields[0]),
+			class:  AddressClass(fields[1]),
+		})
+	}
+
+	return ranges
+}
+
+// ParseIPAddress parses the IPv4 or IPv6 address, IPv4-mapped IPv6 addresses are converted to IPv4.
+// Addresses with a zone are not valid.
+func ParseIPAddress(ipAddress string) (netip.Addr, bool) {
+	addr, err := netip.ParseAddr(ipAddress)
+	if err != nil || addr.Zone() != "" {
+		return netip.Addr{}, false
+	}
+
+	return addr.Unmap(), true
+}
+
+// CanonicalIPAddress returns the canonical form of the IP address (RFC 5952 for IPv6),
+// so the same address is stored and looked up by one string.
+func CanonicalIPAddress(ipAddress string) (string, bool) {
+	addr, ok := ParseIPAddress(ipAddress)
+	if !ok {
+		return "", false
+	}
+
+	return addr.String(), true
+}
+
+func IsValidIPAddress(ipAddress string) bool {
+	_, ok := ParseIPAddress(ipAddress)
+	return ok
+}
+
+// ClassifyIPAddress returns the class of the special-purpose range of the address or ClassPublic.
+func ClassifyIPAddress(addr netip.Addr) AddressClass {
+	addr = addr.Unmap()
+	for _, r := range specialRanges {
+		if r.prefix.Contains(addr) {
+			return r.class
+		}
+	}
+
+	return ClassPublic
+}
+
+// ExtractIPAddress returns the first IP address of the string, e.g. of the URL path.
+func ExtractIPAddress(s string) string {
+	tokens := strings.FieldsFunc(s, func(r rune) bool {
+		return !(r >= '0' && r <= '9' || r >= 'a' && r <= 'f' || r >= 'A' && r <= 'F' || r == '.' || r == ':')
+	})
+	for _, token := range tokens {
+		if IsValidIPAddress(token) {
+			return token
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,239 @@
+package processes
+
+import (
+	"net/netip"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vio/internal/models"
+)
+
+func TestIsValidIPAddress(t *testing.T) {
+	tests := []struct {
+		name      string
+		ipAddress string
+		want      bool
+	}{
+		{
+			name:      "Correct IPv4 address",
+			ipAddress: "70.95.73.73",
+			want:      true,
+		},
+		{
+			name:      "Correct IPv6 address 1",
+			ipAddress: "2001:0db8:85a3:0000:0000:8a2e:0370:7334",
+			want:      true,
+		},
+		{
+			name:      "Correct IPv6 address 2",
+			ipAddress: "2001:db8:85a3::8a2e:370:7334",
+			want:      true,
+		},
+		{
+			name:      "Empty IP address",
+			ipAddress: "",
+			want:      false,
+		},
+		{
+			name:      "Error IPv4 address",
+			ipAddress: "70.95.73.7399",
+			want:      false,
+		},
+		{
+			name:      "Error IPv6 address",
+			ipAddress: "2001:ZZ8:85a3::8a2e:370:7334",
+			want:      false,
+		},
+		{
+			name:      "IPv4 address with leading zeros",
+			ipAddress: "70.095.73.73",
+			want:      false,
+		},
+		{
+			name:      "IPv6 address with zone",
+			ipAddress: "fe80::1%eth0",
+			want:      false,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := IsValidIPAddress(tt.ipAddress); got != tt.want {
+				t.Errorf("IsValidIPAddress() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCanonicalIPAddress(t *testing.T) {
+	tests := []struct {
+		name      string
+		ipAddress string
+		want      string
+		wantOK    bool
+	}{
+		{
+			name:      "IPv4 address",
+			ipAddress: "70.95.73.73",
+			want:      "70.95.73.73",
+			wantOK:    true,
+		},
+		{
+			name:      "IPv6 address with leading zeros",
+			ipAddress: "2001:0DB8:85a3:0000:0000:8a2e:0370:7334",
+			want:      "2001:db8:85a3::8a2e:370:7334",
+			wantOK:    true,
+		},
+		{
+			name:      "IPv4-mapped IPv6 address",
+			ipAddress: "::ffff:70.95.73.73",
+			want:      "70.95.73.73",
+			wantOK:    true,
+		},
+		{
+			name:      "Invalid address",
+			ipAddress: "not your IP address",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, ok := CanonicalIPAddress(tt.ipAddress)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestClassifyIPAddress(t *testing.T) {
+	tests := []struct {
+		ipAddress string
+		want      AddressClass
+	}{
+		{ipAddress: "70.95.73.73", want: ClassPublic},
+		{ipAddress: "2a00:1450:4001:81b::200e", want: ClassPublic},
+		{ipAddress: "10.1.2.3", want: ClassPrivate},
+		{ipAddress: "172.31.255.255", want: ClassPrivate},
+		{ipAddress: "172.32.0.1", want: ClassPublic},
+		{ipAddress: "192.168.0.1", want: ClassPrivate},
+		{ipAddress: "100.64.0.1", want: ClassPrivate},
+		{ipAddress: "fd12:3456::1", want: ClassPrivate},
+		{ipAddress: "127.0.0.1", want: ClassLoopback},
+		{ipAddress: "::1", want: ClassLoopback},
+		{ipAddress: "::ffff:127.0.0.1", want: ClassLoopback},
+		{ipAddress: "169.254.1.1", want: ClassLinkLocal},
+		{ipAddress: "fe80::1", want: ClassLinkLocal},
+		{ipAddress: "224.0.0.251", want: ClassMulticast},
+		{ipAddress: "ff02::1", want: ClassMulticast},
+		{ipAddress: "0.0.0.0", want: ClassUnspecified},
+		{ipAddress: "::", want: ClassUnspecified},
+		{ipAddress: "192.0.2.10", want: ClassDocumentation},
+		{ipAddress: "198.51.100.10", want: ClassDocumentation},
+		{ipAddress: "203.0.113.10", want: ClassDocumentation},
+		{ipAddress: "2001:db8::1", want: ClassDocumentation},
+		{ipAddress: "0.1.2.3", want: ClassReserved},
+		{ipAddress: "198.18.0.1", want: ClassReserved},
+		{ipAddress: "240.0.0.1", want: ClassReserved},
+		{ipAddress: "255.255.255.255", want: ClassReserved},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.ipAddress, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.want, ClassifyIPAddress(netip.MustParseAddr(tt.ipAddress)))
+		})
+	}
+}
+
+func TestExtractIPAddress(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{
+			name:   "Valid IP address",
+			source: "api/geolocation/70.95.73.73",
+			want:   "70.95.73.73",
+		},
+		{
+			name:   "Empty IP address",
+			source: "api/geolocation/",
+			want:   "",
+		},
+		{
+			name:   "Error IP address",
+			source: "api/geolocation/70.95.73.739",
+			want:   "",
+		},
+		{
+			name:   "Valid IPv6 address",
+			source: "/api/geolocation/2001:db8::1?fields=city",
+			want:   "2001:db8::1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equalf(t, tt.want, ExtractIPAddress(tt.source), "ExtractIPAddress(%v)", tt.source)
+		})
+	}
+}
+
+var benchmarkIPAddresses = []string{
+	"70.95.73.73",
+	"125.159.20.54",
+	"2001:db8:85a3::8a2e:370:7334",
+	"not your IP address",
+	"",
+}
+
+// legacyIPRegex is the regular expression IP addresses were validated with before, kept for the benchmarks.
+var legacyIPRegex = regexp.MustCompile(`^(([0-9]|[1-9][0-9]|1[0-9]{2}|2[0-4][0-9]|25[0-5])\.){3}([0-9]|[1-9][0-9]|1[0-9]{2}|2[0-4][0-9]|25[0-5])$|^([0-9a-fA-F]{1,4}:){7,7}[0-9a-fA-F]{1,4}$|^([0-9a-fA-F]{1,4}:){1,7}:([0-9a-fA-F]{1,4}:){1,7}[0-9a-fA-F]{1,4}$|^([0-9a-fA-F]{1,4}:){1,6}:[0-9a-fA-F]{1,4}$|^([0-9a-fA-F]{1,4}:){1,5}(:[0-9a-fA-F]{1,4}){1,2}$|^([0-9a-fA-F]{1,4}:){1,4}(:[0-9a-fA-F]{1,4}){1,3}$|^([0-9a-fA-F]{1,4}:){1,3}(:[0-9a-fA-F]{1,4}){1,4}$|^([0-9a-fA-F]{1,4}:){1,2}(:[0-9a-fA-F]{1,4}){1,5}$|^([0-9a-fA-F]{1,4}:){1,1}(:[0-9a-fA-F]{1,4}){1,6}$|^:(:[0-9a-fA-F]{1,4}){1,7}$|^::$`)
+
+func BenchmarkIsValidIPAddress(b *testing.B) {
+	b.Run("netip", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			IsValidIPAddress(benchmarkIPAddresses[i%len(benchmarkIPAddresses)])
+		}
+	})
+	b.Run("regexp", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			legacyIPRegex.MatchString(benchmarkIPAddresses[i%len(benchmarkIPAddresses)])
+		}
+	})
+}
+
+func BenchmarkValidationPolicy(b *testing.B) {
+	policy := ValidationPolicy{Bogons: BogonsReject}
+	var statistics models.LoadStatistics
+	for i := 0; i < b.N; i++ {
+		location := models.Location{IPAddress: benchmarkIPAddresses[i%len(benchmarkIPAddresses)]}
+		policy.validate(&location, &statistics)
+	}
+}
+
+func BenchmarkExtractIPAddress(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		ExtractIPAddress("/api/geolocation/70.95.73.73")
+	}
+}
+
+func Test_specialRanges(t *testing.T) {
+	known := map[AddressClass]bool{
+		ClassPrivate: true, ClassLoopback: true, ClassLinkLocal: true, ClassMulticast: true,
+		ClassUnspecified: true, ClassDocumentation: true, ClassReserved: true,
+	}
+	require.NotEmpty(t, specialRanges)
+	for _, r := range specialRanges {
+		assert.Truef(t, known[r.class], "unknown class %q of %s", r.class, r.prefix)
+		assert.Equalf(t, r.prefix.Masked(), r.prefix, "prefix %s has host bits", r.prefix)
+	}
+}
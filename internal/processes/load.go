@@ -1,96 +1,88 @@
 package processes
 
 import (
-	"encoding/csv"
+	"context"
 	"errors"
 	"fmt"
-	"io"
 	"os"
 	"path/filepath"
-	"regexp"
+	"runtime"
 	"strconv"
 	"time"
 
 	"vio/internal/models"
 )
 
-// Regular expression to check IPv4 and IPv6.
-var ipRegex = regexp.MustCompile(`^(([0-9]|[1-9][0-9]|1[0-9]{2}|2[0-4][0-9]|25[0-5])\.){3}([0-9]|[1-9][0-9]|1[0-9]{2}|2[0-4][0-9]|25[0-5])$|^([0-9a-fA-F]{1,4}:){7,7}[0-9a-fA-F]{1,4}$|^([0-9a-fA-F]{1,4}:){1,7}:([0-9a-fA-F]{1,4}:){1,7}[0-9a-fA-F]{1,4}$|^([0-9a-fA-F]{1,4}:){1,6}:[0-9a-fA-F]{1,4}$|^([0-9a-fA-F]{1,4}:){1,5}(:[0-9a-fA-F]{1,4}){1,2}$|^([0-9a-fA-F]{1,4}:){1,4}(:[0-9a-fA-F]{1,4}){1,3}$|^([0-9a-fA-F]{1,4}:){1,3}(:[0-9a-fA-F]{1,4}){1,4}$|^([0-9a-fA-F]{1,4}:){1,2}(:[0-9a-fA-F]{1,4}){1,5}$|^([0-9a-fA-F]{1,4}:){1,1}(:[0-9a-fA-F]{1,4}){1,6}$|^:(:[0-9a-fA-F]{1,4}){1,7}$|^::$`)
-
-func loadData(path string) ([]models.Location, *models.LoadStatistics, error) {
+// loadData reads the CSV file or the CSV files of the directory with the number of goroutines,
+// records are validated by the policy.
+// Files are parsed concurrently, large files are split into chunks parsed by separate goroutines.
+// Results are merged in the order of files and records, so a newer entry replaces the previous one
+// and locations are returned in the order of their first appearance.
+// When the context is cancelled, locations read so far are returned with the context error.
+func loadData(ctx context.Context, path string, workers int, opts ImportOptions, progress *Progress) ([]models.Location, *models.LoadStatistics, error) {
 	startTime := time.Now()
-	fileInfos, err := os.ReadDir(path)
+	filePaths, err := sourceFiles(path, opts.Format)
 	if err != nil {
-		return nil, nil, fmt.Errorf("error reading directory: %v", err)
+		return nil, nil, err
 	}
 
+	progress.setFiles(len(filePaths), totalSize(filePaths))
+
+	files := planSourceFiles(filePaths, max(1, workers), opts)
+	chunks := parseChunks(ctx, files, max(1, workers), opts, progress)
+
 	var errs []error
-	uniqLocations := make(map[string]models.Location)
 	locations := make([]models.Location, 0)
+	// index of the location of the IP address in locations.
+	index := make(map[string]int)
 	var loadStatistics models.LoadStatistics
-	for _, fileInfo := range fileInfos {
-		if !fileInfo.IsDir() && filepath.Ext(fileInfo.Name()) == ".csv" {
-			filePath := filepath.Join(path, fileInfo.Name())
-			file, err := os.Open(filePath)
-			if err != nil {
-				errs = append(errs, fmt.Errorf("error opening file %s: %v", fileInfo.Name(), err))
 
-				continue
-			}
-			loadStatistics.FilesCount++
-
-			reader := csv.NewReader(file)
-
-			// Skip the header of the CSV file.
-			_, err = reader.Read()
-			if err != nil {
-				errs = append(errs, err)
-
-				break
-			}
-
-			for {
-				record, err := reader.Read()
-				if err != nil {
-					if !errors.Is(err, io.EOF) {
-						errs = append(errs, err)
+	c := 0
+merge:
+	for _, f := range files {
+		if f.err != nil {
+			errs = append(errs, f.err)
+			continue
+		}
+		fileChunks := chunks[c : c+len(f.chunks)]
+		c += len(f.chunks)
+		if !fileChunks[0].started {
+			// The context is cancelled before the file is read.
+			break
+		}
+		loadStatistics.FilesCount++
+
+		for _, result := range fileChunks {
+			for _, location := range result.locations {
+				if i, ok := index[location.IPAddress]; ok {
+					loadStatistics.Duplicates++
+					if opts.Duplicates != DuplicatesFirst {
+						locations[i] = location
 					}
-
-					break
-				}
-
-				latitude, _ := strconv.ParseFloat(record[4], 64)
-				longitude, _ := strconv.ParseFloat(record[5], 64)
-				mysteryValue, _ := strconv.ParseInt(record[6], 10, 64)
-
-				location := models.Location{
-					IPAddress:    record[0],
-					CountryCode:  record[1],
-					Country:      record[2],
-					City:         record[3],
-					Latitude:     latitude,
-					Longitude:    longitude,
-					MysteryValue: mysteryValue,
+				} else {
+					index[location.IPAddress] = len(locations)
+					locations = append(locations, location)
 				}
+			}
+			loadStatistics.Accepted += int64(len(result.locations))
+			addValidationStatistics(&loadStatistics, result.statistics)
 
-				if !IsValidIPAddress(location.IPAddress) {
-					loadStatistics.Discarded++
-					continue
-				}
+			if result.headerErr != nil {
+				errs = append(errs, result.headerErr)
 
-				uniqLocations[location.IPAddress] = location
-				loadStatistics.Accepted++
+				break merge
 			}
+			if result.err != nil {
+				// Records after the error are skipped as if the file was read to the error.
+				errs = append(errs, result.err)
 
-			errClose := file.Close()
-			if errClose != nil {
-				errs = append(errs, fmt.Errorf("error closing file %s: %v", fileInfo.Name(), errClose))
+				break
 			}
 		}
 	}
 
-	for _, location := range uniqLocations {
-		locations = append(locations, location)
+	if ctx.Err() != nil {
+		errs = append(errs, ctx.Err())
 	}
 
 	err = errors.Join(errs...)
@@ -100,17 +92,78 @@ func loadData(path string) ([]models.Location, *models.LoadStatistics, error) {
 	return locations, &loadStatistics, err
 }
 
-func IsValidIPAddress(ipAddress string) bool {
-	return ipRegex.MatchString(ipAddress)
+// parseWorkers returns the number of goroutines parsing files for the number of goroutines writing locations.
+// Parsing is bound by the CPU, so more goroutines than CPU cores do not help.
+func parseWorkers(maxWorkers int) int {
+	switch {
+	case maxWorkers == ParallelAdaptive || maxWorkers == 0:
+		return runtime.NumCPU()
+	case maxWorkers < 0:
+		return 1
+	default:
+		return min(maxWorkers, runtime.NumCPU())
+	}
+}
+
+// parseRecord converts the record of the source file by the column indexes of the location fields,
+// invalid numbers are zero.
+func parseRecord(record []string, columns []int) models.Location {
+	field := func(i int) string {
+		if columns[i] < 0 {
+			return ""
+		}
+		return record[columns[i]]
+	}
+	latitude, _ := strconv.ParseFloat(field(4), 64)
+	longitude, _ := strconv.ParseFloat(field(5), 64)
+	mysteryValue, _ := strconv.ParseInt(field(6), 10, 64)
+
+	return models.Location{
+		IPAddress:    field(0),
+		CountryCode:  field(1),
+		Country:      field(2),
+		City:         field(3),
+		Latitude:     latitude,
+		Longitude:    longitude,
+		MysteryValue: mysteryValue,
+	}
 }
 
-func ExtractIPAddress(s string) string {
-	re := regexp.MustCompile(`\b(?:25[0-5]|2[0-4][0-9]|[01]?[0-9][0-9]?)\.(?:25[0-5]|2[0-4][0-9]|[01]?[0-9][0-9]?)\.(?:25[0-5]|2[0-4][0-9]|[01]?[0-9][0-9]?)\.(?:25[0-5]|2[0-4][0-9]|[01]?[0-9][0-9]?)\b`)
-	match := re.FindString(s)
-	if match == "" {
-		return ""
+// sourceFiles returns the path if it is a file, or the files of the directory with the extension of the format.
+func sourceFiles(path string, format SourceFormat) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading source: %v", err)
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	fileInfos, err := os.ReadDir(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading directory: %v", err)
 	}
-	return match
+
+	var filePaths []string
+	for _, fileInfo := range fileInfos {
+		if !fileInfo.IsDir() && filepath.Ext(fileInfo.Name()) == format.Extension() {
+			filePaths = append(filePaths, filepath.Join(path, fileInfo.Name()))
+		}
+	}
+
+	return filePaths, nil
+}
+
+// totalSize returns the total size of the files in bytes, files failed to stat are skipped.
+func totalSize(filePaths []string) int64 {
+	var size int64
+	for _, filePath := range filePaths {
+		if info, err := os.Stat(filePath); err == nil {
+			size += info.Size()
+		}
+	}
+
+	return size
 }
 
 func addTimeDurations(time1, time2 string) (string, error) {
@@ -1,8 +1,12 @@
 package processes
 
 import (
+	"context"
+	"fmt"
+	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
 
 	"vio/internal/models"
@@ -48,6 +52,8 @@ func Test_loadData(t *testing.T) {
 					Latitude:     -49.16675918861615,
 					Longitude:    -86.05920084416894,
 					MysteryValue: 2559997162,
+					QualityScore: 40,
+					QualityFlags: []string{FlagCountryMismatch, FlagOutsideCountry},
 				},
 				{
 					IPAddress:    "125.159.20.54",
@@ -57,6 +63,8 @@ func Test_loadData(t *testing.T) {
 					Latitude:     -78.2274228596799,
 					Longitude:    -163.26218895343357,
 					MysteryValue: 1337885276,
+					QualityScore: 40,
+					QualityFlags: []string{FlagCountryMismatch, FlagOutsideCountry},
 				},
 			},
 			wantStatistics: &models.LoadStatistics{
@@ -76,7 +84,7 @@ func Test_loadData(t *testing.T) {
 			t.Parallel()
 
 			path := readFixture(t, tt.path)
-			got, got1, err := loadData(path)
+			got, got1, err := loadData(context.Background(), path, 1, ImportOptions{}, nil)
 			if !tt.wantError {
 				assert.NoError(t, err)
 			} else {
@@ -95,41 +103,56 @@ func Test_loadData(t *testing.T) {
 	}
 }
 
-func TestIsValidIPAddress(t *testing.T) {
+func Test_loadData_chunks(t *testing.T) {
+	chunkSize := minChunkSize
+	minChunkSize = 256
+	t.Cleanup(func() { minChunkSize = chunkSize })
+
+	const header = "ip_address,country_code,country,city,latitude,longitude,mystery_value\n"
+	var first, second strings.Builder
+	first.WriteString(header)
+	second.WriteString(header)
+	for i := 0; i < 100; i++ {
+		// IP addresses are repeated in both files, the newer entry must win.
+		fmt.Fprintf(&first, "10.0.0.%d,NL,Netherlands,Amsterdam,52.37,4.89,%d\n", i%60, i)
+		if i%10 == 0 {
+			first.WriteString("not an IP address,NL,Netherlands,Amsterdam,0,0,0\n")
+			// A quoted field with a line break and a comma must stay in one chunk.
+			fmt.Fprintf(&first, "10.1.0.%d,NL,\"Netherlands,\nthe\",\"\"\"Den Haag\"\"\",52.07,4.3,%d\n", i, i)
+		}
+		fmt.Fprintf(&second, "10.0.0.%d,BE,Belgium,Brussels,50.85,4.35,%d\n", i%40+30, i)
+	}
+
 	tests := []struct {
-		name      string
-		ipAddress string
-		want      bool
+		name            string
+		files           []string
+		wantStatistics  *models.LoadStatistics
+		wantErrorString string
 	}{
 		{
-			name:      "Correct IPv4 address",
-			ipAddress: "70.95.73.73",
-			want:      true,
-		},
-		{
-			name:      "Correct IPv6 address 1",
-			ipAddress: "2001:0db8:85a3:0000:0000:8a2e:0370:7334",
-			want:      true,
-		},
-		{
-			name:      "Correct IPv6 address 2",
-			ipAddress: "2001:db8:85a3::8a2e:370:7334",
-			want:      true,
-		},
-		{
-			name:      "Empty IP address",
-			ipAddress: "",
-			want:      false,
-		},
-		{
-			name:      "Error IPv4 address",
-			ipAddress: "70.95.73.7399",
-			want:      false,
+			name:  "Duplicates across chunks and files",
+			files: []string{first.String(), second.String()},
+			wantStatistics: &models.LoadStatistics{
+				FilesCount: 2,
+				Accepted:   210,
+				Discarded:  10,
+				Total:      220,
+				Normalized: 10,
+				Duplicates: 130,
+			},
 		},
 		{
-			name:      "Error IPv6 address",
-			ipAddress: "2001:ZZ8:85a3::8a2e:370:7334",
-			want:      false,
+			name:  "Parse error in a later chunk",
+			files: []string{first.String() + "10.2.0.1,NL,Netherlands\n" + second.String()[len(header):]},
+			wantStatistics: &models.LoadStatistics{
+				FilesCount: 1,
+				Accepted:   110,
+				Discarded:  10,
+				Total:      120,
+				Normalized: 10,
+				Duplicates: 40,
+			},
+			wantErrorString: "record on line 132: wrong number of fields",
 		},
 	}
 	for _, tt := range tests {
@@ -137,13 +160,81 @@ func TestIsValidIPAddress(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
 
-			if got := IsValidIPAddress(tt.ipAddress); got != tt.want {
-				t.Errorf("IsValidIPAddress() = %v, want %v", got, tt.want)
+			path := t.TempDir()
+			for i, content := range tt.files {
+				require.NoError(t, os.WriteFile(filepath.Join(path, fmt.Sprintf("input_%d.csv", i)), []byte(content), 0o600))
+			}
+
+			// One goroutine reads the files sequentially as a whole.
+			wantResult, _, _ := loadData(context.Background(), path, 1, ImportOptions{}, nil)
+			for _, workers := range []int{1, 3, 8} {
+				progress := &Progress{}
+				got, gotStatistics, err := loadData(context.Background(), path, workers, ImportOptions{}, progress)
+				if tt.wantErrorString == "" {
+					require.NoError(t, err)
+				} else {
+					assert.EqualError(t, err, tt.wantErrorString)
+				}
+
+				if diff := cmp.Diff(wantResult, got); diff != "" {
+					t.Fatalf("Result mismatch with %d workers\n%s", workers, diff)
+				}
+				diff := cmp.Diff(tt.wantStatistics, gotStatistics, cmpopts.IgnoreFields(models.LoadStatistics{}, "LoadTime"))
+				if diff != "" {
+					t.Fatalf("LoadStatistics mismatch with %d workers\n%s", workers, diff)
+				}
+				snapshot := progress.Snapshot()
+				assert.Equal(t, snapshot.BytesTotal, snapshot.BytesRead)
+				assert.Equal(t, int64(len(tt.files)), snapshot.FilesRead)
 			}
 		})
 	}
 }
 
+func Test_splitFile(t *testing.T) {
+	content := "ip_address,city\n1.1.1.1,\"Den\nHaag\"\n2.2.2.2,Amsterdam\n3.3.3.3,\"Rotter\n\ndam\"\n4.4.4.4,Utrecht\n"
+	path := filepath.Join(t.TempDir(), "input.csv")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	header, chunks, err := splitFile(file, int64(len(content)), 4, FormatCSV)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ip_address", "city"}, header)
+
+	// Chunks start after the header or a record, never inside the quoted line breaks.
+	want := []chunk{
+		{start: 0, end: 35, line: 0},
+		{start: 35, end: 53, line: 3},
+		{start: 53, end: 75, line: 4},
+		{start: 75, end: 91, line: 7},
+	}
+	assert.Equal(t, want, chunks)
+}
+
+func Test_splitFile_tsv(t *testing.T) {
+	content := "ip_address\tcity\n1.1.1.1\t6\" Street\n2.2.2.2\tAmsterdam\n3.3.3.3\t\"Quoted\n4.4.4.4\tUtrecht\n"
+	path := filepath.Join(t.TempDir(), "input.tsv")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	header, chunks, err := splitFile(file, int64(len(content)), 4, FormatTSV)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ip_address", "city"}, header)
+
+	// Quotes of TSV files are a part of the values, every line break ends a record.
+	want := []chunk{
+		{start: 0, end: 34, line: 0},
+		{start: 34, end: 52, line: 2},
+		{start: 52, end: 68, line: 3},
+		{start: 68, end: 84, line: 4},
+	}
+	assert.Equal(t, want, chunks)
+}
+
 func Test_addTimeDurations(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -180,35 +271,6 @@ func Test_addTimeDurations(t *testing.T) {
 	}
 }
 
-func TestExtractIPAddress(t *testing.T) {
-	tests := []struct {
-		name   string
-		source string
-		want   string
-	}{
-		{
-			name:   "Valid IP address",
-			source: "api/geolocation/70.95.73.73",
-			want:   "70.95.73.73",
-		},
-		{
-			name:   "Empty IP address",
-			source: "api/geolocation/",
-			want:   "",
-		},
-		{
-			name:   "Error IP address",
-			source: "api/geolocation/70.95.73.739",
-			want:   "",
-		},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			assert.Equalf(t, tt.want, ExtractIPAddress(tt.source), "ExtractIPAddress(%v)", tt.source)
-		})
-	}
-}
-
 func readFixture(t *testing.T, name string) string {
 	t.Helper()
 
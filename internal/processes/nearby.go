@@ -0,0 +1,88 @@
+package processes
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+
+	"vio/internal/lib/geo"
+	"vio/internal/models"
+)
+
+// ErrSpatialIndexNotReady is returned when the spatial index has not been loaded yet.
+var ErrSpatialIndexNotReady = errors.New("spatial index is not ready")
+
+// SpatialIndex keeps all locations in memory with the k-d tree over their coordinates.
+type SpatialIndex struct {
+	mu        sync.RWMutex
+	locations []models.Location
+	tree      *geo.KDTree
+}
+
+// NewSpatialIndex creates an empty spatial index, use Load to fill it.
+func NewSpatialIndex() *SpatialIndex {
+	return &SpatialIndex{}
+}
+
+// Load reads all locations from the database and replaces the content of the index.
+func (s *SpatialIndex) Load(ctx context.Context, db *sql.DB) error {
+	rows, err := db.QueryContext(ctx, "SELECT "+SQLLocationColumns+" FROM location")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	locations := make([]models.Location, 0)
+	for rows.Next() {
+		var loc models.Location
+		if err := scanLocation(rows, &loc); err != nil {
+			return err
+		}
+		locations = append(locations, loc)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	s.Set(locations)
+
+	return nil
+}
+
+// Set replaces the content of the index.
+func (s *SpatialIndex) Set(locations []models.Location) {
+	points := make([]geo.Point, len(locations))
+	for i, loc := range locations {
+		points[i] = geo.Point{Latitude: loc.Latitude, Longitude: loc.Longitude}
+	}
+	tree := geo.NewKDTree(points)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.locations = locations
+	s.tree = tree
+}
+
+// Nearby returns up to limit locations closest to the point within radiusKm ordered by distance.
+// A non-positive radius means the distance is not limited.
+func (s *SpatialIndex) Nearby(point geo.Point, radiusKm float64, limit int) ([]models.NearbyLocation, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.tree == nil {
+		return nil, ErrSpatialIndexNotReady
+	}
+
+	neighbors := s.tree.Nearest(point, radiusKm, limit)
+	result := make([]models.NearbyLocation, 0, len(neighbors))
+	for _, neighbor := range neighbors {
+		result = append(result, models.NearbyLocation{
+			Location:   s.locations[neighbor.Index],
+			DistanceKm: neighbor.DistanceKm,
+		})
+	}
+
+	return result, nil
+}
@@ -0,0 +1,44 @@
+package processes
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vio/internal/lib/geo"
+)
+
+func TestSpatialIndex_Nearby(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	index := NewSpatialIndex()
+	_, err = index.Nearby(geo.Point{}, 0, 10)
+	assert.ErrorIs(t, err, ErrSpatialIndexNotReady)
+
+	columns := []string{"IPAddress", "CountryCode", "Country", "City", "Latitude", "Longitude", "MysteryValue", "QualityScore", "QualityFlags"}
+	rows := sqlmock.NewRows(columns).
+		AddRow("1.1.1.1", "NL", "Netherlands", "Amsterdam", 52.3676, 4.9041, 1, 100, "{}").
+		AddRow("2.2.2.2", "DE", "Germany", "Berlin", 52.5200, 13.4050, 2, 100, "{}").
+		AddRow("3.3.3.3", "US", "United States", "New York", 40.7128, -74.0060, 3, 100, "{}")
+	mock.ExpectQuery("SELECT (.+) FROM location").WillReturnRows(rows)
+
+	require.NoError(t, index.Load(context.Background(), db))
+
+	got, err := index.Nearby(geo.Point{Latitude: 52.0907, Longitude: 5.1214}, 1000, 10)
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+	assert.Equal(t, "1.1.1.1", got[0].IPAddress)
+	assert.InDelta(t, 34.2, got[0].DistanceKm, 0.1)
+	assert.Equal(t, "2.2.2.2", got[1].IPAddress)
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,67 @@
+package processes
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+	"golang.org/x/text/unicode/norm"
+
+	"vio/internal/models"
+)
+
+// maxTextLength is the length in characters of the VARCHAR(250) country and city columns.
+const maxTextLength = 250
+
+// normalizeLocation normalizes the country and the city of the location, the original values
+// of the changed fields are kept for auditing. It returns true when a field is changed.
+func normalizeLocation(location *models.Location) bool {
+	country := normalizeText(location.Country)
+	city := normalizeText(location.City)
+	changed := false
+	if country != location.Country {
+		location.CountryOriginal = location.Country
+		location.Country = country
+		changed = true
+	}
+	if city != location.City {
+		location.CityOriginal = location.City
+		location.City = city
+		changed = true
+	}
+
+	return changed
+}
+
+// normalizeText converts the name to the Unicode NFC form, removes control and format characters,
+// collapses and trims white space and capitalizes the words of names written in one case
+// ("new neva" and "NEW NEVA" become "New Neva", "DuBuquemouth" is kept). The result is cut
+// to maxTextLength characters.
+func normalizeText(s string) string {
+	s = norm.NFC.String(s)
+	s = strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsControl(r):
+			// Tabs and line breaks separate words.
+			return ' '
+		case unicode.Is(unicode.Cf, r):
+			return -1
+		default:
+			return r
+		}
+	}, s)
+	s = strings.Join(strings.Fields(s), " ")
+
+	hasUpper := strings.IndexFunc(s, unicode.IsUpper) >= 0
+	hasLower := strings.IndexFunc(s, unicode.IsLower) >= 0
+	if hasUpper != hasLower {
+		s = cases.Title(language.Und).String(s)
+	}
+
+	if runes := []rune(s); len(runes) > maxTextLength {
+		s = strings.TrimRight(string(runes[:maxTextLength]), " ")
+	}
+
+	return s
+}
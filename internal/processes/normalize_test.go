@@ -0,0 +1,45 @@
+package processes
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"vio/internal/models"
+)
+
+func Test_normalizeText(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{name: "Normalized", value: "New Neva", want: "New Neva"},
+		{name: "Lower case", value: "new neva", want: "New Neva"},
+		{name: "Upper case", value: "NEW NEVA", want: "New Neva"},
+		{name: "Mixed case is kept", value: "DuBuquemouth", want: "DuBuquemouth"},
+		{name: "White space", value: "  New \t Neva\n", want: "New Neva"},
+		{name: "Control and format characters", value: "\ufeffNew\x00Neva\u200b", want: "New Neva"},
+		{name: "NFC", value: "Re\u0301union", want: "R\u00e9union"},
+		{name: "Empty", value: " \t", want: ""},
+		{name: "Maximum length", value: strings.Repeat("Ü", 249) + " ab", want: strings.Repeat("Ü", 249)},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.want, normalizeText(tt.value))
+		})
+	}
+}
+
+func Test_normalizeLocation(t *testing.T) {
+	location := models.Location{Country: "Netherlands", City: " den haag"}
+	assert.True(t, normalizeLocation(&location))
+	assert.Equal(t, models.Location{Country: "Netherlands", City: "Den Haag", CityOriginal: " den haag"}, location)
+
+	assert.False(t, normalizeLocation(&location))
+	assert.Equal(t, " den haag", location.CityOriginal)
+}
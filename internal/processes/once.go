@@ -2,6 +2,7 @@ package processes
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -9,50 +10,175 @@ import (
 	"strconv"
 
 	"vio/internal/database"
+	"vio/internal/models"
 )
 
-func RunOnce(path string, connectString string, parallelFlag string) ([]byte, error) {
-	locations, loadStatistics, err := loadData(path)
-	if err != nil {
-		return nil, err
+// ParallelAdaptive is the number of workers selecting the adaptive worker pool.
+const ParallelAdaptive = -2
+
+// ImportOptions of the import.
+type ImportOptions struct {
+	// Parallel is the number of goroutines writing locations, -1 writes them sequentially,
+	// 0 uses the number of CPU cores, ParallelAdaptive sizes the pool by the throughput.
+	Parallel int
+	// BatchSize is the number of locations written with one statement, 1 or less writes them one by one.
+	BatchSize int
+	// Format of the source files, FormatCSV by default.
+	Format SourceFormat
+	// Columns maps the location fields to the header columns, empty reads the columns of the default layout.
+	Columns ColumnMapping
+	// Duplicates is the handling of records of an IP address repeated in the files, DuplicatesLast by default.
+	Duplicates DuplicatePolicy
+	// Validation of the records.
+	Validation ValidationPolicy
+	// PoolReserve is the number of connections of a limited pool left to other users of the database,
+	// the adaptive worker pool is capped at the rest.
+	PoolReserve int
+}
+
+// ParseParallel parses the parallel option: "auto" for the adaptive worker pool,
+// -1 for sequential writes, N for the number of workers, 0 for the number of CPU cores.
+func ParseParallel(value string) (int, error) {
+	if value == "auto" {
+		return ParallelAdaptive, nil
+	}
+	parallel, err := strconv.Atoi(value)
+	if err != nil || parallel < -1 {
+		return 0, fmt.Errorf("invalid parallel %q: must be auto, -1, 0 or a number of goroutines", value)
 	}
-	fmt.Fprintf(os.Stderr, "finished reading: %s\n", loadStatistics.LoadTime)
-	var loadTimeProcessStr string
 
-	db, err := database.GetDB(connectString)
-	if err != nil {
-		return nil, err
+	return parallel, nil
+}
+
+// PoolOptions returns the connection pool options of a database used only by the import:
+// the pool follows the number of writing goroutines, sequential writes keep the defaults.
+func PoolOptions(parallel int) database.Options {
+	workers := parallel
+	switch {
+	case parallel == ParallelAdaptive:
+		workers = Adaptive.MaxWorkers
+	case parallel < 0:
+		return database.Options{}
+	case parallel == 0:
+		workers = runtime.NumCPU()
 	}
-	defer db.Close()
 
-	ctx := context.Background()
+	return database.Options{MaxOpenConns: workers, MaxIdleConns: workers}
+}
 
-	if parallelFlag == "-1" {
+// RunOnce imports the files and returns the statistics as JSON, the progress may be nil.
+// When the context is cancelled, partial statistics with the aborted status are returned with the error.
+func RunOnce(ctx context.Context, path string, connectString string, opts ImportOptions, progress *Progress) ([]byte, error) {
+	switch {
+	case opts.Parallel == ParallelAdaptive:
+		fmt.Fprintf(os.Stderr, "start with adaptive parallel working\n")
+	case opts.Parallel < 0:
 		fmt.Fprintf(os.Stderr, "no parallel working\n")
-		loadTimeProcessStr, err = process(ctx, db, &locations)
-	} else {
+	default:
 		// Getting the number of processors in the system.
-		maxWorkers, _ := strconv.Atoi(parallelFlag)
-		if maxWorkers == 0 {
-			maxWorkers = runtime.NumCPU()
+		if opts.Parallel == 0 {
+			opts.Parallel = runtime.NumCPU()
 		}
-		fmt.Fprintf(os.Stderr, "start with %d parallel working\n", maxWorkers)
+		fmt.Fprintf(os.Stderr, "start with %d parallel working\n", opts.Parallel)
+	}
+
+	db, err := database.GetDB(connectString, PoolOptions(opts.Parallel))
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
 
-		loadTimeProcessStr, err = processParallelWithMaxProcs(ctx, db, &locations, maxWorkers)
+	loadStatistics, errImport := Import(ctx, db, path, opts, progress)
+	if loadStatistics == nil {
+		return nil, errImport
 	}
+	fmt.Fprintf(os.Stderr, "%s: %s\n", loadStatistics.Status, loadStatistics.LoadTime)
 
+	jsonStatistics, err := json.Marshal(loadStatistics)
 	if err != nil {
 		return nil, err
 	}
-	fmt.Fprintf(os.Stderr, "finished db inserting: %s\n", loadTimeProcessStr)
-	loadStatistics.LoadTime, err = addTimeDurations(loadStatistics.LoadTime, loadTimeProcessStr)
+
+	return jsonStatistics, errImport
+}
+
+// Import loads the source file or the source files of the directory into the database with the options.
+// When the context is cancelled, the import stops and partial statistics with the aborted status
+// are returned together with the context error. Locations failed to write are counted in the statistics
+// returned together with the write errors.
+func Import(ctx context.Context, db *sql.DB, path string, opts ImportOptions, progress *Progress) (*models.LoadStatistics, error) {
+	if progress == nil {
+		progress = &Progress{}
+	}
+
+	progress.setPhase(PhaseReading)
+	locations, loadStatistics, err := loadData(ctx, path, parseWorkers(opts.Parallel), opts, progress)
+	if ctx.Err() != nil {
+		return aborted(loadStatistics, progress, "0s", ctx.Err())
+	}
 	if err != nil {
 		return nil, err
 	}
-	jsonStatistics, err := json.Marshal(loadStatistics)
+
+	progress.setPhase(PhaseWriting)
+	progress.setRowsToWrite(len(locations))
+
+	var loadTimeProcessStr string
+	switch {
+	case opts.Parallel == ParallelAdaptive:
+		loadTimeProcessStr, loadStatistics.Workers, err = processAdaptive(ctx, db, &locations, opts.BatchSize, opts.Duplicates, opts.PoolReserve, progress)
+	case opts.Parallel < 0:
+		loadTimeProcessStr, err = process(ctx, db, &locations, opts.BatchSize, opts.Duplicates, progress)
+		loadStatistics.Workers = 1
+	default:
+		maxWorkers := opts.Parallel
+		if maxWorkers == 0 {
+			maxWorkers = runtime.NumCPU()
+		}
+		loadTimeProcessStr, err = processParallelWithMaxProcs(ctx, db, &locations, maxWorkers, opts.BatchSize, opts.Duplicates, progress)
+		loadStatistics.Workers = maxWorkers
+	}
+	if ctx.Err() != nil {
+		return aborted(loadStatistics, progress, loadTimeProcessStr, ctx.Err())
+	}
+	if err != nil && loadTimeProcessStr == "" {
+		// The statement is not prepared, nothing is written.
+		return nil, err
+	}
+	// Statistics are returned together with write errors to report failed locations.
+	errWrite := err
+	progress.setPhase(PhaseDone)
+
+	loadStatistics.LoadTime, err = addTimeDurations(loadStatistics.LoadTime, loadTimeProcessStr)
 	if err != nil {
 		return nil, err
 	}
+	loadStatistics.Status = StatusCompleted
+	if errWrite != nil {
+		loadStatistics.Status = StatusCompletedWithErrors
+	}
+	setWriteStatistics(loadStatistics, progress)
+
+	return loadStatistics, errWrite
+}
+
+// aborted completes the statistics of the import stopped by the context.
+func aborted(loadStatistics *models.LoadStatistics, progress *Progress, loadTimeProcessStr string, err error) (*models.LoadStatistics, error) {
+	if loadStatistics == nil {
+		loadStatistics = &models.LoadStatistics{LoadTime: "0s"}
+	}
+	loadStatistics.Status = StatusAborted
+	setWriteStatistics(loadStatistics, progress)
+	if loadTime, errTime := addTimeDurations(loadStatistics.LoadTime, loadTimeProcessStr); errTime == nil {
+		loadStatistics.LoadTime = loadTime
+	}
+	progress.setPhase(PhaseDone)
+
+	return loadStatistics, err
+}
 
-	return jsonStatistics, nil
+func setWriteStatistics(loadStatistics *models.LoadStatistics, progress *Progress) {
+	loadStatistics.Written = progress.rowsWritten.Load()
+	loadStatistics.Retries = progress.retries.Load()
+	loadStatistics.Failed = progress.rowsFailed.Load()
 }
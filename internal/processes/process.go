@@ -9,43 +9,71 @@ import (
 	"vio/internal/models"
 )
 
-var SQLInsert = `INSERT INTO location (ip_address, country_code, country, city, latitude, longitude, mystery_value)
-VALUES ($1, $2, $3, $4, $5, $6, $7)
-ON CONFLICT (ip_address) DO UPDATE
+// SQLInsert upserts the location, a newer entry replaces the stored one.
+var SQLInsert = sqlInsertLocation + sqlUpsertLocation
+
+// SQLInsertFirst inserts the location unless the IP address is already stored.
+var SQLInsertFirst = sqlInsertLocation + sqlKeepLocation
+
+var sqlInsertLocation = `INSERT INTO location (ip_address, country_code, country, city, latitude, longitude, mystery_value, country_original, city_original,
+	quality_score, quality_flags)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, string_to_array($11, ','))
+`
+
+// sqlUpsertLocation replaces the stored location of the IP address.
+var sqlUpsertLocation = `ON CONFLICT (ip_address) DO UPDATE
 SET
 	country_code = EXCLUDED.country_code,
 	country = EXCLUDED.country,
 	city = EXCLUDED.city,
 	latitude = EXCLUDED.latitude,
 	longitude = EXCLUDED.longitude,
-	mystery_value = EXCLUDED.mystery_value
+	mystery_value = EXCLUDED.mystery_value,
+	country_original = EXCLUDED.country_original,
+	city_original = EXCLUDED.city_original,
+	quality_score = EXCLUDED.quality_score,
+	quality_flags = EXCLUDED.quality_flags
+`
+
+// sqlKeepLocation keeps the stored location of the IP address.
+var sqlKeepLocation = `ON CONFLICT (ip_address) DO NOTHING
 `
 
-var SQLSelect = `SELECT * FROM location WHERE ip_address = $1`
+var SQLSelect = `SELECT ` + SQLLocationColumns + ` FROM location WHERE ip_address = $1`
 
-func process(ctx context.Context, db *sql.DB, locations *[]models.Location) (string, error) {
+func process(ctx context.Context, db *sql.DB, locations *[]models.Location, batchSize int, duplicates DuplicatePolicy, progress *Progress) (string, error) {
 	startTime := time.Now()
-	stmt, err := db.Prepare(SQLInsert)
+	ws, err := prepareWrite(db, batchSize, duplicates, progress)
 	if err != nil {
 		return "", err
 	}
-	defer stmt.Close()
+	defer ws.Close()
 
 	var errs []error
 
+	writer := ws.newWriter()
 	for _, loc := range *locations {
-		_, err := stmt.ExecContext(ctx, loc.IPAddress, loc.CountryCode, loc.Country, loc.City, loc.Latitude, loc.Longitude, loc.MysteryValue)
-		if err != nil {
+		if ctx.Err() != nil {
+			break
+		}
+		if err := writer.write(ctx, loc); err != nil {
 			errs = append(errs, err)
 		}
 	}
+	if ctx.Err() != nil {
+		errs = append(errs, ctx.Err())
+	} else if err := writer.flush(ctx); err != nil {
+		errs = append(errs, err)
+	}
 	finishTime := time.Since(startTime).String()
 
 	return finishTime, errors.Join(errs...)
 }
 
-func GetLocation(db *sql.DB, ipAddress string) (*models.Location, error) {
-	rows, err := db.Query(SQLSelect, ipAddress)
+// GetLocation reads the location of the IP address, an empty location is returned when it is not found.
+// The query is cancelled with the context.
+func GetLocation(ctx context.Context, db *sql.DB, ipAddress string) (*models.Location, error) {
+	rows, err := db.QueryContext(ctx, SQLSelect, ipAddress)
 	if err != nil {
 		return nil, err
 	}
@@ -54,19 +82,18 @@ func GetLocation(db *sql.DB, ipAddress string) (*models.Location, error) {
 	var loc models.Location
 
 	for rows.Next() {
-		err := rows.Scan(
-			&loc.IPAddress,
-			&loc.CountryCode,
-			&loc.Country,
-			&loc.City,
-			&loc.Latitude,
-			&loc.Longitude,
-			&loc.MysteryValue,
-		)
-		if err != nil {
+		if err := scanLocation(rows, &loc); err != nil {
 			return nil, err
 		}
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
 	return &loc, nil
 }
+
+// nullIfEmpty stores an empty string as NULL.
+func nullIfEmpty(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
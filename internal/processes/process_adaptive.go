@@ -0,0 +1,228 @@
+package processes
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"vio/internal/models"
+)
+
+// AdaptivePolicy of the worker pool sized by the observed throughput and database latency.
+type AdaptivePolicy struct {
+	MinWorkers int
+	MaxWorkers int
+	// Interval between pool size adjustments.
+	Interval time.Duration
+	// LatencyFactor is the ratio to the lowest observed write latency considered as database saturation.
+	LatencyFactor float64
+	// MinGain is the relative throughput growth required to keep added workers.
+	MinGain float64
+	// Hold is the number of intervals without growth after added workers did not increase the throughput.
+	Hold int
+}
+
+// Adaptive is the policy of the adaptive parallel mode.
+var Adaptive = AdaptivePolicy{
+	MinWorkers:    2,
+	MaxWorkers:    64,
+	Interval:      time.Second,
+	LatencyFactor: 2,
+	MinGain:       0.05,
+	Hold:          5,
+}
+
+// forPool caps the workers at the connections of a limited pool minus the reserve left to other users,
+// a worker holds at most one connection, so waiting for a connection is not counted as write latency.
+func (p AdaptivePolicy) forPool(maxOpen, reserve int) AdaptivePolicy {
+	if maxOpen <= 0 {
+		return p
+	}
+	p.MaxWorkers = max(1, min(p.MaxWorkers, maxOpen-reserve))
+	p.MinWorkers = min(p.MinWorkers, p.MaxWorkers)
+
+	return p
+}
+
+// poolController decides the pool size with hill climbing: workers are added while the throughput grows,
+// removed when added workers do not help or the write latency shows the database is saturated.
+type poolController struct {
+	policy         AdaptivePolicy
+	workers        int
+	prevThroughput float64
+	minLatency     time.Duration
+	grown          bool
+	hold           int
+}
+
+func newPoolController(policy AdaptivePolicy) *poolController {
+	return &poolController{
+		policy:  policy,
+		workers: policy.MinWorkers,
+	}
+}
+
+// next returns the pool size for the throughput (rows per second) and the average write latency of the last interval.
+func (c *poolController) next(throughput float64, latency time.Duration) int {
+	if latency > 0 && (c.minLatency == 0 || latency < c.minLatency) {
+		c.minLatency = latency
+	}
+	saturated := c.minLatency > 0 && float64(latency) > c.policy.LatencyFactor*float64(c.minLatency)
+
+	switch {
+	case saturated:
+		c.shrink()
+	case c.grown && throughput < c.prevThroughput*(1+c.policy.MinGain):
+		// Added workers did not increase the throughput.
+		c.shrink()
+		c.hold = c.policy.Hold
+	case c.hold > 0:
+		c.hold--
+		c.grown = false
+	default:
+		c.grow()
+	}
+	c.prevThroughput = throughput
+
+	return c.workers
+}
+
+func (c *poolController) grow() {
+	step := max(1, c.workers/4)
+	c.workers = min(c.policy.MaxWorkers, c.workers+step)
+	c.grown = true
+}
+
+func (c *poolController) shrink() {
+	step := max(1, c.workers/4)
+	c.workers = max(c.policy.MinWorkers, c.workers-step)
+	c.grown = false
+}
+
+// latencyStats accumulates write latencies of an interval.
+type latencyStats struct {
+	total atomic.Int64
+	count atomic.Int64
+}
+
+func (l *latencyStats) add(d time.Duration) {
+	l.total.Add(int64(d))
+	l.count.Add(1)
+}
+
+// reset returns the average latency and the number of writes since the previous reset.
+func (l *latencyStats) reset() (time.Duration, int64) {
+	total := l.total.Swap(0)
+	count := l.count.Swap(0)
+	if count == 0 {
+		return 0, 0
+	}
+
+	return time.Duration(total / count), count
+}
+
+// processAdaptive writes locations with a worker pool resized every interval of the policy.
+// The connection pool may be shared with the API, so its limits are left as configured:
+// the workers are capped at the open connections limit minus the reserve.
+// It returns the process time and the final pool size.
+func processAdaptive(ctx context.Context, db *sql.DB, locations *[]models.Location, batchSize int, duplicates DuplicatePolicy, reserve int, progress *Progress) (string, int, error) {
+	startTime := time.Now()
+	policy := Adaptive.forPool(db.Stats().MaxOpenConnections, reserve)
+	ws, err := prepareWrite(db, batchSize, duplicates, progress)
+	if err != nil {
+		return "", 0, err
+	}
+	defer ws.Close()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var errs []error
+	var latency latencyStats
+
+	addErr := func(err error) {
+		if err != nil && ctx.Err() == nil {
+			mu.Lock()
+			errs = append(errs, err)
+			mu.Unlock()
+		}
+	}
+	jobQueue := make(chan models.Location)
+	worker := func(stop <-chan struct{}) {
+		defer wg.Done()
+		writer := ws.newWriter()
+		// A stopped worker writes its batch before leaving the pool.
+		defer func() { addErr(writer.flush(ctx)) }()
+		for {
+			select {
+			case <-stop:
+				return
+			case loc, ok := <-jobQueue:
+				if !ok {
+					return
+				}
+				writeStart := time.Now()
+				err := writer.write(ctx, loc)
+				latency.add(time.Since(writeStart))
+				addErr(err)
+			}
+		}
+	}
+
+	var stops []chan struct{}
+	resize := func(workers int) {
+		for len(stops) < workers {
+			stop := make(chan struct{})
+			stops = append(stops, stop)
+			wg.Add(1)
+			go worker(stop)
+		}
+		for len(stops) > workers {
+			close(stops[len(stops)-1])
+			stops = stops[:len(stops)-1]
+		}
+	}
+
+	controller := newPoolController(policy)
+	resize(controller.workers)
+
+	// Filling the channel with tasks.
+	queued := make(chan struct{})
+	go func() {
+		defer close(queued)
+		defer close(jobQueue)
+		for _, loc := range *locations {
+			select {
+			case jobQueue <- loc:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(policy.Interval)
+	defer ticker.Stop()
+
+	lastTick := time.Now()
+	for done := false; !done; {
+		select {
+		case <-queued:
+			done = true
+		case now := <-ticker.C:
+			avgLatency, count := latency.reset()
+			throughput := float64(count) / now.Sub(lastTick).Seconds()
+			lastTick = now
+			resize(controller.next(throughput, avgLatency))
+		}
+	}
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		errs = append(errs, ctx.Err())
+	}
+	finishTime := time.Since(startTime).String()
+
+	return finishTime, controller.workers, errors.Join(errs...)
+}
@@ -0,0 +1,173 @@
+package processes
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vio/internal/database"
+	"vio/internal/models"
+)
+
+func Test_poolController_next(t *testing.T) {
+	policy := AdaptivePolicy{MinWorkers: 2, MaxWorkers: 10, LatencyFactor: 2, MinGain: 0.05, Hold: 2}
+
+	type sample struct {
+		throughput  float64
+		latency     time.Duration
+		wantWorkers int
+	}
+	tests := []struct {
+		name    string
+		samples []sample
+	}{
+		{
+			name: "Grow while throughput grows",
+			samples: []sample{
+				{throughput: 100, latency: 10 * time.Millisecond, wantWorkers: 3},
+				{throughput: 150, latency: 10 * time.Millisecond, wantWorkers: 4},
+				{throughput: 200, latency: 10 * time.Millisecond, wantWorkers: 5},
+				{throughput: 250, latency: 10 * time.Millisecond, wantWorkers: 6},
+				{throughput: 300, latency: 10 * time.Millisecond, wantWorkers: 7},
+				{throughput: 350, latency: 10 * time.Millisecond, wantWorkers: 8},
+				{throughput: 400, latency: 10 * time.Millisecond, wantWorkers: 10},
+				{throughput: 500, latency: 10 * time.Millisecond, wantWorkers: 10},
+			},
+		},
+		{
+			name: "Shrink and hold when added workers do not help",
+			samples: []sample{
+				{throughput: 100, latency: 10 * time.Millisecond, wantWorkers: 3},
+				{throughput: 101, latency: 10 * time.Millisecond, wantWorkers: 2},
+				{throughput: 100, latency: 10 * time.Millisecond, wantWorkers: 2},
+				{throughput: 100, latency: 10 * time.Millisecond, wantWorkers: 2},
+				{throughput: 100, latency: 10 * time.Millisecond, wantWorkers: 3},
+			},
+		},
+		{
+			name: "Shrink when database is saturated",
+			samples: []sample{
+				{throughput: 100, latency: 10 * time.Millisecond, wantWorkers: 3},
+				{throughput: 200, latency: 10 * time.Millisecond, wantWorkers: 4},
+				{throughput: 300, latency: 30 * time.Millisecond, wantWorkers: 3},
+				{throughput: 300, latency: 30 * time.Millisecond, wantWorkers: 2},
+				{throughput: 300, latency: 30 * time.Millisecond, wantWorkers: 2},
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			c := newPoolController(policy)
+			for i, s := range tt.samples {
+				assert.Equal(t, s.wantWorkers, c.next(s.throughput, s.latency), "sample %d", i)
+			}
+		})
+	}
+}
+
+func TestAdaptivePolicy_forPool(t *testing.T) {
+	policy := AdaptivePolicy{MinWorkers: 2, MaxWorkers: 64}
+	tests := []struct {
+		name    string
+		maxOpen int
+		reserve int
+		wantMin int
+		wantMax int
+	}{
+		{name: "Unlimited pool", maxOpen: 0, reserve: 10, wantMin: 2, wantMax: 64},
+		{name: "Limited pool", maxOpen: 50, reserve: 10, wantMin: 2, wantMax: 40},
+		{name: "Pool larger than the workers", maxOpen: 100, reserve: 10, wantMin: 2, wantMax: 64},
+		{name: "Reserve of the whole pool", maxOpen: 10, reserve: 10, wantMin: 1, wantMax: 1},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := policy.forPool(tt.maxOpen, tt.reserve)
+			assert.Equal(t, tt.wantMin, got.MinWorkers)
+			assert.Equal(t, tt.wantMax, got.MaxWorkers)
+		})
+	}
+}
+
+func TestPoolOptions(t *testing.T) {
+	assert.Equal(t, database.Options{}, PoolOptions(-1))
+	assert.Equal(t, database.Options{MaxOpenConns: 30, MaxIdleConns: 30}, PoolOptions(30))
+	assert.Equal(t, database.Options{MaxOpenConns: runtime.NumCPU(), MaxIdleConns: runtime.NumCPU()}, PoolOptions(0))
+	assert.Equal(t, database.Options{MaxOpenConns: Adaptive.MaxWorkers, MaxIdleConns: Adaptive.MaxWorkers}, PoolOptions(ParallelAdaptive))
+}
+
+func TestProcessAdaptive(t *testing.T) {
+	adaptive := Adaptive
+	// The stub database re-prepares the statement on every new connection, so the pool is kept at one worker
+	// to make the expectations deterministic. Resizing is covered by Test_poolController_next.
+	Adaptive = AdaptivePolicy{MinWorkers: 1, MaxWorkers: 1, Interval: 5 * time.Millisecond, LatencyFactor: 2, MinGain: 0.05, Hold: 1}
+	t.Cleanup(func() { Adaptive = adaptive })
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(5)
+
+	locations := make([]models.Location, 20)
+	prepare := mock.ExpectPrepare(regexp.QuoteMeta(SQLInsert))
+	for i := range locations {
+		locations[i] = models.Location{IPAddress: fmt.Sprintf("10.0.0.%d", i)}
+		prepare.ExpectExec().WillDelayFor(time.Millisecond).WillReturnResult(sqlmock.NewResult(1, 1))
+	}
+
+	progress := &Progress{}
+	resultTime, workers, err := processAdaptive(context.Background(), db, &locations, 1, DuplicatesLast, 2, progress)
+	require.NoError(t, err)
+	assert.True(t, len(resultTime) > 0, "unexpected finish time")
+	assert.Equal(t, 1, workers)
+	assert.Equal(t, int64(20), progress.Snapshot().RowsWritten)
+	assert.Equal(t, 5, db.Stats().MaxOpenConnections, "connection limit of the shared pool is changed")
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestParseParallel(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    int
+		wantErr bool
+	}{
+		{name: "Adaptive", value: "auto", want: ParallelAdaptive},
+		{name: "Sequential", value: "-1", want: -1},
+		{name: "CPU cores", value: "0", want: 0},
+		{name: "Goroutines", value: "30", want: 30},
+		{name: "Negative", value: "-2", wantErr: true},
+		{name: "Not a number", value: "fast", wantErr: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := ParseParallel(tt.value)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -17,28 +17,32 @@ type Worker struct {
 	Result   chan error
 }
 
-func (w *Worker) start(ctx context.Context, wg *sync.WaitGroup, stmt *sql.Stmt, mu *sync.Mutex, errs *[]error) {
+func (w *Worker) start(ctx context.Context, wg *sync.WaitGroup, writer locationWriter, mu *sync.Mutex, errs *[]error) {
 	defer wg.Done()
 
-	for loc := range w.JobQueue {
-		_, err := stmt.ExecContext(ctx, loc.IPAddress, loc.CountryCode, loc.Country, loc.City, loc.Latitude, loc.Longitude, loc.MysteryValue)
-		if err != nil {
+	addErr := func(err error) {
+		// The queue is drained without writing when the context is cancelled.
+		if err != nil && ctx.Err() == nil {
 			mu.Lock()
 			*errs = append(*errs, err)
 			mu.Unlock()
 		}
 	}
+	for loc := range w.JobQueue {
+		addErr(writer.write(ctx, loc))
+	}
+	addErr(writer.flush(ctx))
 
 	w.Result <- nil
 }
 
-func processParallelWithMaxProcs(ctx context.Context, db *sql.DB, locations *[]models.Location, maxWorkers int) (string, error) {
+func processParallelWithMaxProcs(ctx context.Context, db *sql.DB, locations *[]models.Location, maxWorkers int, batchSize int, duplicates DuplicatePolicy, progress *Progress) (string, error) {
 	startTime := time.Now()
-	stmt, err := db.Prepare(SQLInsert)
+	ws, err := prepareWrite(db, batchSize, duplicates, progress)
 	if err != nil {
 		return "", err
 	}
-	defer stmt.Close()
+	defer ws.Close()
 
 	var wg sync.WaitGroup
 	var mu sync.Mutex
@@ -55,12 +59,15 @@ func processParallelWithMaxProcs(ctx context.Context, db *sql.DB, locations *[]m
 			Result:   results,
 		}
 		wg.Add(1)
-		go worker.start(ctx, &wg, stmt, &mu, &errs)
+		go worker.start(ctx, &wg, ws.newWriter(), &mu, &errs)
 	}
 
 	// Filling the channel with tasks.
 	go func() {
 		for _, loc := range *locations {
+			if ctx.Err() != nil {
+				break
+			}
 			jobQueue <- loc
 		}
 		close(jobQueue)
@@ -77,6 +84,9 @@ func processParallelWithMaxProcs(ctx context.Context, db *sql.DB, locations *[]m
 		}
 	}
 
+	if ctx.Err() != nil {
+		errs = append(errs, ctx.Err())
+	}
 	finishTime := time.Since(startTime).String()
 
 	return finishTime, errors.Join(errs...)
@@ -24,7 +24,7 @@ func TestProcessParallelWithMaxProcs(t *testing.T) {
 	mock.ExpectPrepare(expectedSQL).ExpectExec().WillReturnResult(sqlmock.NewResult(1, 1))
 
 	testFunction := func(locations *[]models.Location, maxWorkers int) (string, error) {
-		return processParallelWithMaxProcs(ctx, db, locations, maxWorkers)
+		return processParallelWithMaxProcs(ctx, db, locations, maxWorkers, 1, DuplicatesLast, nil)
 	}
 
 	locations := []models.Location{
@@ -2,12 +2,14 @@ package processes
 
 import (
 	"context"
+	"errors"
 	"regexp"
 	"testing"
 	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/stretchr/testify/assert"
 
 	"vio/internal/models"
@@ -21,7 +23,7 @@ func TestGetLocation(t *testing.T) {
 	defer db.Close()
 
 	testFunction := func(ipAddress string) (*models.Location, error) {
-		return GetLocation(db, ipAddress)
+		return GetLocation(context.Background(), db, ipAddress)
 	}
 
 	expectedLocation := &models.Location{
@@ -32,10 +34,12 @@ func TestGetLocation(t *testing.T) {
 		Latitude:     40.7128,
 		Longitude:    -74.0060,
 		MysteryValue: 1234567,
+		QualityScore: 90,
+		QualityFlags: []string{"country_corrected"},
 	}
 
-	rows := sqlmock.NewRows([]string{"IPAddress", "CountryCode", "Country", "City", "Latitude", "Longitude", "MysteryValue"}).
-		AddRow(expectedLocation.IPAddress, expectedLocation.CountryCode, expectedLocation.Country, expectedLocation.City, expectedLocation.Latitude, expectedLocation.Longitude, expectedLocation.MysteryValue)
+	rows := sqlmock.NewRows([]string{"IPAddress", "CountryCode", "Country", "City", "Latitude", "Longitude", "MysteryValue", "QualityScore", "QualityFlags"}).
+		AddRow(expectedLocation.IPAddress, expectedLocation.CountryCode, expectedLocation.Country, expectedLocation.City, expectedLocation.Latitude, expectedLocation.Longitude, expectedLocation.MysteryValue, expectedLocation.QualityScore, "{country_corrected}")
 
 	mock.ExpectQuery("SELECT (.+) FROM location WHERE ip_address = (.+)").WithArgs("127.0.0.1").WillReturnRows(rows)
 
@@ -55,6 +59,23 @@ func TestGetLocation(t *testing.T) {
 	}
 }
 
+func TestGetLocation_RowError(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	rows := sqlmock.NewRows([]string{"IPAddress", "CountryCode", "Country", "City", "Latitude", "Longitude", "MysteryValue", "QualityScore", "QualityFlags"}).
+		AddRow("127.0.0.1", "US", "United States", "New York", 40.7128, -74.0060, 1234567, 90, "{}").
+		RowError(0, errors.New("connection reset by peer"))
+	mock.ExpectQuery("SELECT (.+) FROM location WHERE ip_address = (.+)").WithArgs("127.0.0.1").WillReturnRows(rows)
+
+	got, err := GetLocation(context.Background(), db, "127.0.0.1")
+	assert.EqualError(t, err, "connection reset by peer")
+	assert.Nil(t, got)
+}
+
 func TestProcess(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
@@ -65,7 +86,7 @@ func TestProcess(t *testing.T) {
 	ctx := context.Background()
 	testFunction := func(locations *[]models.Location) (time.Duration, error) {
 		startTime := time.Now()
-		_, err := process(ctx, db, locations)
+		_, err := process(ctx, db, locations, 1, DuplicatesLast, nil)
 		return time.Since(startTime), err
 	}
 
@@ -85,7 +106,7 @@ func TestProcess(t *testing.T) {
 
 	mock.ExpectPrepare(expectedSQL).ExpectExec().WithArgs(
 		locations[0].IPAddress, locations[0].CountryCode, locations[0].Country, locations[0].City,
-		locations[0].Latitude, locations[0].Longitude, locations[0].MysteryValue,
+		locations[0].Latitude, locations[0].Longitude, locations[0].MysteryValue, nil, nil, 0, "",
 	).WillReturnResult(sqlmock.NewResult(1, 1))
 
 	resultTime, resultErr := testFunction(&locations)
@@ -97,3 +118,63 @@ func TestProcess(t *testing.T) {
 		t.Errorf("there were unfulfilled expectations: %s", err)
 	}
 }
+
+func TestImportAborted(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	progress := &Progress{}
+	gotStatistics, err := Import(ctx, db, readFixture(t, "process_data_good"), ImportOptions{Parallel: -1}, progress)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	wantStatistics := &models.LoadStatistics{Status: StatusAborted}
+	diff := cmp.Diff(wantStatistics, gotStatistics, cmpopts.IgnoreFields(models.LoadStatistics{}, "LoadTime"))
+	if diff != "" {
+		t.Fatal("LoadStatistics mismatch\n", diff)
+	}
+	assert.Equal(t, PhaseDone, progress.Snapshot().Phase)
+
+	// Nothing is written after cancellation.
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestImport(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	prepare := mock.ExpectPrepare(regexp.QuoteMeta(SQLInsert))
+	prepare.ExpectExec().WillReturnResult(sqlmock.NewResult(1, 1))
+	prepare.ExpectExec().WillReturnResult(sqlmock.NewResult(1, 1))
+
+	gotStatistics, err := Import(context.Background(), db, readFixture(t, "process_data_good"), ImportOptions{Parallel: -1}, nil)
+	assert.NoError(t, err)
+
+	wantStatistics := &models.LoadStatistics{
+		Status:     StatusCompleted,
+		FilesCount: 1,
+		Accepted:   2,
+		Discarded:  2,
+		Total:      4,
+		Written:    2,
+		Workers:    1,
+	}
+	diff := cmp.Diff(wantStatistics, gotStatistics, cmpopts.IgnoreFields(models.LoadStatistics{}, "LoadTime"))
+	if diff != "" {
+		t.Fatal("LoadStatistics mismatch\n", diff)
+	}
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
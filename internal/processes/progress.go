@@ -0,0 +1,140 @@
+package processes
+
+import (
+	"sync/atomic"
+
+	"vio/internal/models"
+)
+
+// Statuses of finished imports.
+const (
+	StatusCompleted           = "completed"
+	StatusCompletedWithErrors = "completed_with_errors"
+	StatusAborted             = "aborted"
+)
+
+const (
+	PhaseReading = "reading"
+	PhaseWriting = "writing"
+	PhaseDone    = "done"
+)
+
+// Progress counts processed rows of a running import, it is safe for concurrent use.
+// Methods of a nil Progress do nothing.
+type Progress struct {
+	phase      atomic.Value
+	filesTotal atomic.Int64
+	filesRead  atomic.Int64
+	bytesTotal atomic.Int64
+	// bytesRead is the number of bytes read from all files, files are read concurrently.
+	bytesRead   atomic.Int64
+	file        atomic.Value
+	fileSize    atomic.Int64
+	fileOffset  atomic.Int64
+	rowsRead    atomic.Int64
+	rowsWritten atomic.Int64
+	rowsToWrite atomic.Int64
+	retries     atomic.Int64
+	rowsFailed  atomic.Int64
+}
+
+// Snapshot returns the current state of the import.
+func (p *Progress) Snapshot() models.ImportProgress {
+	if p == nil {
+		return models.ImportProgress{}
+	}
+	phase, _ := p.phase.Load().(string)
+	file, _ := p.file.Load().(string)
+
+	return models.ImportProgress{
+		Phase:         phase,
+		FilesTotal:    p.filesTotal.Load(),
+		FilesRead:     p.filesRead.Load(),
+		BytesTotal:    p.bytesTotal.Load(),
+		BytesRead:     p.bytesRead.Load(),
+		File:          file,
+		FileSize:      p.fileSize.Load(),
+		FileBytesRead: p.fileOffset.Load(),
+		RowsRead:      p.rowsRead.Load(),
+		RowsWritten:   p.rowsWritten.Load(),
+		RowsToWrite:   p.rowsToWrite.Load(),
+		Retries:       p.retries.Load(),
+		RowsFailed:    p.rowsFailed.Load(),
+	}
+}
+
+func (p *Progress) setPhase(phase string) {
+	if p != nil {
+		p.phase.Store(phase)
+	}
+}
+
+func (p *Progress) setFiles(count int, size int64) {
+	if p != nil {
+		p.filesTotal.Store(int64(count))
+		p.bytesTotal.Store(size)
+	}
+}
+
+// chunkRead adds n bytes read from the file, fileOffset is the number of bytes of the file read so far.
+func (p *Progress) chunkRead(name string, size int64, fileOffset int64, n int64) {
+	if p != nil {
+		p.bytesRead.Add(n)
+		p.file.Store(name)
+		p.fileSize.Store(size)
+		p.fileOffset.Store(fileOffset)
+	}
+}
+
+// fileParsed completes the file, the unread bytes (e.g. after a parse error) are counted as read.
+func (p *Progress) fileParsed(name string, size int64, unread int64) {
+	if p != nil {
+		p.bytesRead.Add(unread)
+		p.filesRead.Add(1)
+		p.file.Store(name)
+		p.fileSize.Store(size)
+		p.fileOffset.Store(size)
+	}
+}
+
+func (p *Progress) rowRead() {
+	if p != nil {
+		p.rowsRead.Add(1)
+	}
+}
+
+func (p *Progress) rowWritten() {
+	if p != nil {
+		p.rowsWritten.Add(1)
+	}
+}
+
+func (p *Progress) batchWritten(n int) {
+	if p != nil {
+		p.rowsWritten.Add(int64(n))
+	}
+}
+
+func (p *Progress) retried() {
+	if p != nil {
+		p.retries.Add(1)
+	}
+}
+
+func (p *Progress) rowFailed() {
+	if p != nil {
+		p.rowsFailed.Add(1)
+	}
+}
+
+func (p *Progress) batchFailed(n int) {
+	if p != nil {
+		p.rowsFailed.Add(int64(n))
+	}
+}
+
+func (p *Progress) setRowsToWrite(n int) {
+	if p != nil {
+		p.rowsToWrite.Store(int64(n))
+	}
+}
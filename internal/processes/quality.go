@@ -0,0 +1,78 @@
+package processes
+
+import (
+	"fmt"
+	"strconv"
+
+	"vio/internal/models"
+)
+
+// MaxQualityScore is the score of a location without quality flags.
+const MaxQualityScore = 100
+
+// Quality flags of locations.
+const (
+	FlagMissingCountry     = "missing_country"
+	FlagMissingCity        = "missing_city"
+	FlagZeroCoordinates    = "zero_coordinates"
+	FlagInvalidCoordinates = "invalid_coordinates"
+	FlagCountryMismatch    = "country_mismatch"
+	FlagCountryCorrected   = "country_corrected"
+	FlagReservedAddress    = "reserved_address"
+	FlagOutsideCountry     = "outside_country"
+)
+
+// qualityPenalties are the points the flags take from MaxQualityScore.
+var qualityPenalties = map[string]int{
+	FlagMissingCountry:     30,
+	FlagMissingCity:        20,
+	FlagZeroCoordinates:    40,
+	FlagInvalidCoordinates: 50,
+	FlagCountryMismatch:    30,
+	FlagCountryCorrected:   10,
+	FlagReservedAddress:    30,
+	FlagOutsideCountry:     30,
+}
+
+// ParseMinQuality parses the minimum quality score, an empty value is 0 accepting all locations.
+func ParseMinQuality(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	minQuality, err := strconv.Atoi(value)
+	if err != nil || minQuality < 0 || minQuality > MaxQualityScore {
+		return 0, fmt.Errorf("invalid min_quality %q: must be between 0 and %d", value, MaxQualityScore)
+	}
+
+	return minQuality, nil
+}
+
+// scoreLocation sets the quality flags and the quality score of the validated location,
+// flags set by the checks before (e.g. FlagCountryCorrected) are kept.
+func scoreLocation(location *models.Location, class AddressClass) {
+	if location.CountryCode == "" || location.Country == "" {
+		location.QualityFlags = append(location.QualityFlags, FlagMissingCountry)
+	} else if countries.mismatch(location.CountryCode, location.Country) {
+		location.QualityFlags = append(location.QualityFlags, FlagCountryMismatch)
+	}
+	if location.City == "" {
+		location.QualityFlags = append(location.QualityFlags, FlagMissingCity)
+	}
+	switch {
+	case location.Latitude < -90 || location.Latitude > 90 || location.Longitude < -180 || location.Longitude > 180:
+		location.QualityFlags = append(location.QualityFlags, FlagInvalidCoordinates)
+	case location.Latitude == 0 && location.Longitude == 0:
+		location.QualityFlags = append(location.QualityFlags, FlagZeroCoordinates)
+	case outsideCountry(location):
+		location.QualityFlags = append(location.QualityFlags, FlagOutsideCountry)
+	}
+	if class != ClassPublic {
+		location.QualityFlags = append(location.QualityFlags, FlagReservedAddress)
+	}
+
+	score := MaxQualityScore
+	for _, flag := range location.QualityFlags {
+		score -= qualityPenalties[flag]
+	}
+	location.QualityScore = max(score, 0)
+}
@@ -0,0 +1,103 @@
+package processes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"vio/internal/models"
+)
+
+func Test_scoreLocation(t *testing.T) {
+	tests := []struct {
+		name      string
+		location  models.Location
+		class     AddressClass
+		wantScore int
+		wantFlags []string
+	}{
+		{
+			name:      "Complete location",
+			location:  models.Location{CountryCode: "NL", Country: "Netherlands", City: "Amsterdam", Latitude: 52.37, Longitude: 4.89},
+			class:     ClassPublic,
+			wantScore: 100,
+		},
+		{
+			name:      "Missing city and zero coordinates",
+			location:  models.Location{CountryCode: "NL", Country: "Netherlands"},
+			class:     ClassPublic,
+			wantScore: 40,
+			wantFlags: []string{FlagMissingCity, FlagZeroCoordinates},
+		},
+		{
+			name:      "Inconsistent country",
+			location:  models.Location{CountryCode: "SI", Country: "Nepal", City: "DuBuquemouth", Latitude: 46.05, Longitude: 14.51},
+			class:     ClassPublic,
+			wantScore: 70,
+			wantFlags: []string{FlagCountryMismatch},
+		},
+		{
+			name:      "Outside of the country",
+			location:  models.Location{CountryCode: "SI", Country: "Slovenia", City: "DuBuquemouth", Latitude: -84.87, Longitude: 7.2},
+			class:     ClassPublic,
+			wantScore: 70,
+			wantFlags: []string{FlagOutsideCountry},
+		},
+		{
+			name: "Corrected country",
+			location: models.Location{CountryCode: "CZ", Country: "Czechia", City: "New Neva", Latitude: 50.08, Longitude: 14.43,
+				QualityFlags: []string{FlagCountryCorrected}},
+			class:     ClassPublic,
+			wantScore: 90,
+			wantFlags: []string{FlagCountryCorrected},
+		},
+		{
+			name:      "Score is not negative",
+			location:  models.Location{Latitude: 120, Longitude: 0},
+			class:     ClassPrivate,
+			wantScore: 0,
+			wantFlags: []string{FlagMissingCountry, FlagMissingCity, FlagInvalidCoordinates, FlagReservedAddress},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			location := tt.location
+			scoreLocation(&location, tt.class)
+			assert.Equal(t, tt.wantScore, location.QualityScore)
+			assert.Equal(t, tt.wantFlags, location.QualityFlags)
+		})
+	}
+}
+
+func TestParseMinQuality(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int
+		wantErr bool
+	}{
+		{value: "", want: 0},
+		{value: "0", want: 0},
+		{value: "75", want: 75},
+		{value: "100", want: 100},
+		{value: "-1", wantErr: true},
+		{value: "101", wantErr: true},
+		{value: "high", wantErr: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.value, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := ParseMinQuality(tt.value)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package processes
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/lib/pq"
+
+	"vio/internal/models"
+)
+
+// RetryPolicy of writes failed with transient database errors.
+type RetryPolicy struct {
+	// MaxAttempts is the number of attempts including the first one.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry, it doubles with every retry up to MaxBackoff.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// Retry is the policy of location writes.
+var Retry = RetryPolicy{
+	MaxAttempts:    5,
+	InitialBackoff: 100 * time.Millisecond,
+	MaxBackoff:     5 * time.Second,
+}
+
+// retryableCodes are PostgreSQL error codes of failures that may succeed when repeated.
+var retryableCodes = map[pq.ErrorCode]bool{
+	"40001": true, // serialization_failure
+	"40P01": true, // deadlock_detected
+	"53000": true, // insufficient_resources
+	"53300": true, // too_many_connections
+	"55P03": true, // lock_not_available
+	"57P01": true, // admin_shutdown
+	"57P02": true, // crash_shutdown
+	"57P03": true, // cannot_connect_now
+}
+
+// IsRetryable reports whether the database error is transient:
+// connection failures, deadlocks, serialization failures and server restarts.
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		// Class 08 is connection exception.
+		return pqErr.Code.Class() == "08" || retryableCodes[pqErr.Code]
+	}
+
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// backoff returns the delay before the retry with exponential growth and jitter,
+// the delay is random between a half and the full exponential value.
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	delay := p.InitialBackoff << retry
+	if delay <= 0 || delay > p.MaxBackoff {
+		delay = p.MaxBackoff
+	}
+	half := delay / 2
+
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// insertLocation writes the location, retrying transient errors.
+// Retries and permanent failures are counted in the progress.
+func insertLocation(ctx context.Context, stmt *sql.Stmt, loc models.Location, progress *Progress) error {
+	err := withRetry(ctx, progress, func() error {
+		_, err := stmt.ExecContext(ctx, loc.IPAddress, loc.CountryCode, loc.Country, loc.City, loc.Latitude, loc.Longitude, loc.MysteryValue,
+			nullIfEmpty(loc.CountryOriginal), nullIfEmpty(loc.CityOriginal), loc.QualityScore, strings.Join(loc.QualityFlags, ","))
+		return err
+	})
+	switch {
+	case err == nil:
+		progress.rowWritten()
+	case ctx.Err() == nil:
+		progress.rowFailed()
+	}
+
+	return err
+}
+
+// withRetry runs the write until it succeeds, fails with a permanent error, the attempts of the Retry policy
+// are exhausted or the context is cancelled. Retries are counted in the progress.
+func withRetry(ctx context.Context, progress *Progress, write func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := write()
+		if err == nil || ctx.Err() != nil {
+			return err
+		}
+		if attempt >= Retry.MaxAttempts || !IsRetryable(err) {
+			return err
+		}
+
+		progress.retried()
+		timer := time.NewTimer(Retry.backoff(attempt - 1))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package processes
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"regexp"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vio/internal/models"
+)
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "Connection failure", err: &pq.Error{Code: "08006"}, want: true},
+		{name: "Deadlock", err: &pq.Error{Code: "40P01"}, want: true},
+		{name: "Serialization failure", err: &pq.Error{Code: "40001"}, want: true},
+		{name: "Too many connections", err: &pq.Error{Code: "53300"}, want: true},
+		{name: "Admin shutdown", err: &pq.Error{Code: "57P01"}, want: true},
+		{name: "Unique violation", err: &pq.Error{Code: "23505"}, want: false},
+		{name: "Invalid text representation", err: &pq.Error{Code: "22P02"}, want: false},
+		{name: "Bad connection", err: driver.ErrBadConn, want: true},
+		{name: "Connection reset", err: fmt.Errorf("write: %w", syscall.ECONNRESET), want: true},
+		{name: "Context cancelled", err: context.Canceled, want: false},
+		{name: "Other error", err: errors.New("sql: statement is closed"), want: false},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.want, IsRetryable(tt.err))
+		})
+	}
+}
+
+func TestRetryPolicy_backoff(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 5, InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+
+	for i := 0; i < 100; i++ {
+		delay := policy.backoff(2)
+		assert.GreaterOrEqual(t, delay, 200*time.Millisecond)
+		assert.LessOrEqual(t, delay, 400*time.Millisecond)
+
+		delay = policy.backoff(10)
+		assert.GreaterOrEqual(t, delay, 500*time.Millisecond)
+		assert.LessOrEqual(t, delay, time.Second)
+	}
+}
+
+func Test_insertLocation(t *testing.T) {
+	retry := Retry
+	Retry = RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond}
+	t.Cleanup(func() { Retry = retry })
+
+	tests := []struct {
+		name        string
+		errs        []error
+		wantErr     bool
+		wantWritten int64
+		wantRetries int64
+		wantFailed  int64
+	}{
+		{
+			name:        "Transient error",
+			errs:        []error{&pq.Error{Code: "40P01"}, nil},
+			wantWritten: 1,
+			wantRetries: 1,
+		},
+		{
+			name:       "Permanent error",
+			errs:       []error{&pq.Error{Code: "22P02"}},
+			wantErr:    true,
+			wantFailed: 1,
+		},
+		{
+			name:        "Retries exhausted",
+			errs:        []error{&pq.Error{Code: "57P01"}, &pq.Error{Code: "08006"}, &pq.Error{Code: "08006"}},
+			wantErr:     true,
+			wantRetries: 2,
+			wantFailed:  1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer db.Close()
+
+			prepare := mock.ExpectPrepare(regexp.QuoteMeta(SQLInsert))
+			for _, errExec := range tt.errs {
+				exec := prepare.ExpectExec()
+				if errExec != nil {
+					exec.WillReturnError(errExec)
+				} else {
+					exec.WillReturnResult(sqlmock.NewResult(1, 1))
+				}
+			}
+
+			stmt, err := db.Prepare(SQLInsert)
+			require.NoError(t, err)
+			defer stmt.Close()
+
+			progress := &Progress{}
+			err = insertLocation(context.Background(), stmt, models.Location{IPAddress: "70.95.73.73"}, progress)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			got := progress.Snapshot()
+			assert.Equal(t, tt.wantWritten, got.RowsWritten)
+			assert.Equal(t, tt.wantRetries, got.Retries)
+			assert.Equal(t, tt.wantFailed, got.RowsFailed)
+
+			if err = mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package processes
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"vio/internal/models"
+)
+
+const (
+	// DefaultSearchLimit is the page size used when the limit is not set.
+	DefaultSearchLimit = 100
+	// MaxSearchLimit is the maximum page size of the locations search.
+	MaxSearchLimit = 1000
+
+	earthRadiusKm = 6371.0
+	kmPerDegree   = earthRadiusKm * math.Pi / 180
+)
+
+// ErrInvalidCursor is returned when the pagination cursor can not be decoded.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+var SQLLocationColumns = `ip_address, country_code, country, city, latitude, longitude, mystery_value, quality_score, quality_flags`
+
+// scanLocation reads the SQLLocationColumns of the row.
+func scanLocation(row interface{ Scan(dest ...any) error }, loc *models.Location) error {
+	return row.Scan(
+		&loc.IPAddress,
+		&loc.CountryCode,
+		&loc.Country,
+		&loc.City,
+		&loc.Latitude,
+		&loc.Longitude,
+		&loc.MysteryValue,
+		&loc.QualityScore,
+		pq.Array(&loc.QualityFlags),
+	)
+}
+
+// SQLHaversine calculates the great-circle distance in kilometers between the location and the point
+// given by the latitude (%[1]s) and longitude (%[2]s) placeholders.
+var SQLHaversine = `2 * 6371.0 * ASIN(SQRT(LEAST(1.0,
+	POWER(SIN(RADIANS(latitude - %[1]s) / 2), 2) +
+	COS(RADIANS(%[1]s)) * COS(RADIANS(latitude)) * POWER(SIN(RADIANS(longitude - %[2]s) / 2), 2))))`
+
+// FindLocations returns one page of locations matching the filter ordered by IP address.
+func FindLocations(ctx context.Context, db *sql.DB, filter models.LocationFilter) (*models.LocationPage, error) {
+	limit := normalizeLimit(filter.Limit)
+	filter.Limit = limit
+
+	query, args, err := buildSearchQuery(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	page := &models.LocationPage{
+		Items: make([]models.Location, 0, limit),
+	}
+	for rows.Next() {
+		var loc models.Location
+		if err := scanLocation(rows, &loc); err != nil {
+			return nil, err
+		}
+		page.Items = append(page.Items, loc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// One extra row is requested to find out whether there is a next page.
+	if len(page.Items) > limit {
+		page.Items = page.Items[:limit]
+		page.NextCursor = EncodeCursor(page.Items[limit-1].IPAddress)
+	}
+
+	return page, nil
+}
+
+// EncodeCursor converts the last seen IP address to an opaque pagination cursor.
+func EncodeCursor(ipAddress string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(ipAddress))
+}
+
+// DecodeCursor converts the pagination cursor back to the last seen IP address.
+func DecodeCursor(cursor string) (string, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || !IsValidIPAddress(string(data)) {
+		return "", ErrInvalidCursor
+	}
+
+	return string(data), nil
+}
+
+func normalizeLimit(limit int) int {
+	switch {
+	case limit <= 0:
+		return DefaultSearchLimit
+	case limit > MaxSearchLimit:
+		return MaxSearchLimit
+	default:
+		return limit
+	}
+}
+
+func buildSearchQuery(filter models.LocationFilter) (string, []any, error) {
+	var conditions []string
+	var args []any
+
+	arg := func(value any) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	if filter.CountryCode != "" {
+		conditions = append(conditions, "country_code = "+arg(filter.CountryCode))
+	}
+	if filter.Country != "" {
+		conditions = append(conditions, "country = "+arg(filter.Country))
+	}
+	if filter.City != "" {
+		conditions = append(conditions, "city = "+arg(filter.City))
+	}
+	if filter.MinQuality > 0 {
+		conditions = append(conditions, "quality_score >= "+arg(filter.MinQuality))
+	}
+
+	if box := filter.BoundingBox; box != nil {
+		conditions = append(conditions,
+			fmt.Sprintf("latitude BETWEEN %s AND %s", arg(box.MinLatitude), arg(box.MaxLatitude)))
+		if box.MinLongitude <= box.MaxLongitude {
+			conditions = append(conditions,
+				fmt.Sprintf("longitude BETWEEN %s AND %s", arg(box.MinLongitude), arg(box.MaxLongitude)))
+		} else {
+			// The bounding box crosses the antimeridian.
+			conditions = append(conditions,
+				fmt.Sprintf("(longitude >= %s OR longitude <= %s)", arg(box.MinLongitude), arg(box.MaxLongitude)))
+		}
+	}
+
+	if circle := filter.Circle; circle != nil {
+		// Latitude range narrows the search down with the index before calculating distances.
+		delta := circle.RadiusKm / kmPerDegree
+		conditions = append(conditions,
+			fmt.Sprintf("latitude BETWEEN %s AND %s", arg(circle.Latitude-delta), arg(circle.Latitude+delta)))
+		distance := fmt.Sprintf(SQLHaversine, arg(circle.Latitude), arg(circle.Longitude))
+		conditions = append(conditions, distance+" <= "+arg(circle.RadiusKm))
+	}
+
+	if filter.Cursor != "" {
+		lastIPAddress, err := DecodeCursor(filter.Cursor)
+		if err != nil {
+			return "", nil, err
+		}
+		conditions = append(conditions, "ip_address > "+arg(lastIPAddress))
+	}
+
+	var sb strings.Builder
+	sb.WriteString("SELECT ")
+	sb.WriteString(SQLLocationColumns)
+	sb.WriteString(" FROM location")
+	if len(conditions) > 0 {
+		sb.WriteString(" WHERE ")
+		sb.WriteString(strings.Join(conditions, " AND "))
+	}
+	sb.WriteString(" ORDER BY ip_address LIMIT ")
+	sb.WriteString(arg(filter.Limit + 1))
+
+	return sb.String(), args, nil
+}
@@ -0,0 +1,139 @@
+package processes
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vio/internal/models"
+)
+
+func TestFindLocations(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	columns := []string{"IPAddress", "CountryCode", "Country", "City", "Latitude", "Longitude", "MysteryValue", "QualityScore", "QualityFlags"}
+	rows := sqlmock.NewRows(columns).
+		AddRow("1.1.1.1", "US", "United States", "New York", 40.7128, -74.0060, 1, 100, "{}").
+		AddRow("2.2.2.2", "US", "United States", "New York", 40.7128, -74.0060, 2, 100, "{}").
+		AddRow("3.3.3.3", "US", "United States", "New York", 40.7128, -74.0060, 3, 100, "{}")
+
+	mock.ExpectQuery(`SELECT (.+) FROM location WHERE country_code = \$1 AND ip_address > \$2 ORDER BY ip_address LIMIT \$3`).
+		WithArgs("US", "0.0.0.1", 3).
+		WillReturnRows(rows)
+
+	gotResult, err := FindLocations(context.Background(), db, models.LocationFilter{
+		CountryCode: "US",
+		Cursor:      EncodeCursor("0.0.0.1"),
+		Limit:       2,
+	})
+	require.NoError(t, err)
+
+	assert.Len(t, gotResult.Items, 2)
+	assert.Equal(t, "2.2.2.2", gotResult.Items[1].IPAddress)
+	assert.Equal(t, EncodeCursor("2.2.2.2"), gotResult.NextCursor)
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func Test_buildSearchQuery(t *testing.T) {
+	tests := []struct {
+		name      string
+		filter    models.LocationFilter
+		wantQuery string
+		wantArgs  []any
+		wantError error
+	}{
+		{
+			name:      "Without filters",
+			filter:    models.LocationFilter{Limit: 10},
+			wantQuery: "SELECT " + SQLLocationColumns + " FROM location ORDER BY ip_address LIMIT $1",
+			wantArgs:  []any{11},
+		},
+		{
+			name: "Country and city",
+			filter: models.LocationFilter{
+				Country: "Nepal",
+				City:    "DuBuquemouth",
+				Limit:   10,
+			},
+			wantQuery: "SELECT " + SQLLocationColumns + " FROM location WHERE country = $1 AND city = $2 ORDER BY ip_address LIMIT $3",
+			wantArgs:  []any{"Nepal", "DuBuquemouth", 11},
+		},
+		{
+			name: "Minimum quality",
+			filter: models.LocationFilter{
+				City:       "DuBuquemouth",
+				MinQuality: 70,
+				Limit:      10,
+			},
+			wantQuery: "SELECT " + SQLLocationColumns + " FROM location WHERE city = $1 AND quality_score >= $2 ORDER BY ip_address LIMIT $3",
+			wantArgs:  []any{"DuBuquemouth", 70, 11},
+		},
+		{
+			name: "Bounding box across the antimeridian",
+			filter: models.LocationFilter{
+				BoundingBox: &models.BoundingBox{
+					MinLatitude:  -10,
+					MaxLatitude:  10,
+					MinLongitude: 170,
+					MaxLongitude: -170,
+				},
+				Limit: 10,
+			},
+			wantQuery: "SELECT " + SQLLocationColumns + " FROM location WHERE latitude BETWEEN $1 AND $2 AND (longitude >= $3 OR longitude <= $4) ORDER BY ip_address LIMIT $5",
+			wantArgs:  []any{float64(-10), float64(10), float64(170), float64(-170), 11},
+		},
+		{
+			name: "Invalid cursor",
+			filter: models.LocationFilter{
+				Cursor: "not a cursor",
+				Limit:  10,
+			},
+			wantError: ErrInvalidCursor,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			gotQuery, gotArgs, err := buildSearchQuery(tt.filter)
+			if tt.wantError != nil {
+				assert.ErrorIs(t, err, tt.wantError)
+				return
+			}
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.wantQuery, gotQuery)
+			diff := cmp.Diff(tt.wantArgs, gotArgs)
+			if diff != "" {
+				t.Fatal("args mismatch\n", diff)
+			}
+		})
+	}
+}
+
+func Test_buildSearchQueryCircle(t *testing.T) {
+	gotQuery, gotArgs, err := buildSearchQuery(models.LocationFilter{
+		Circle: &models.Circle{Latitude: 10, Longitude: 20, RadiusKm: kmPerDegree},
+		Limit:  10,
+	})
+	require.NoError(t, err)
+
+	assert.Contains(t, gotQuery, "latitude BETWEEN $1 AND $2")
+	assert.Contains(t, gotQuery, "RADIANS(latitude - $3)")
+	assert.Contains(t, gotQuery, "RADIANS(longitude - $4)")
+	assert.Contains(t, gotQuery, "<= $5")
+	assert.InDelta(t, 9, gotArgs[0], 1e-9)
+	assert.InDelta(t, 11, gotArgs[1], 1e-9)
+}
@@ -0,0 +1,210 @@
+package processes
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// SourceFormat is the format of the source files.
+type SourceFormat string
+
+const (
+	// FormatCSV is comma-separated values in .csv files.
+	FormatCSV SourceFormat = "csv"
+	// FormatTSV is tab-separated values in .tsv files.
+	FormatTSV SourceFormat = "tsv"
+)
+
+// ParseSourceFormat parses the format, an empty value is FormatCSV.
+func ParseSourceFormat(value string) (SourceFormat, error) {
+	switch format := SourceFormat(value); format {
+	case "":
+		return FormatCSV, nil
+	case FormatCSV, FormatTSV:
+		return format, nil
+	default:
+		return "", fmt.Errorf("invalid format %q: must be csv or tsv", value)
+	}
+}
+
+// Extension returns the file name extension of the format.
+func (f SourceFormat) Extension() string {
+	if f == FormatTSV {
+		return ".tsv"
+	}
+
+	return ".csv"
+}
+
+// recordReader reads the records of a source file.
+type recordReader interface {
+	Read() ([]string, error)
+	// InputOffset returns the offset of the end of the last read record.
+	InputOffset() int64
+}
+
+// newReader returns the record reader of the format, records must have fieldsPerRecord fields,
+// 0 takes the number of fields of the first record.
+func (f SourceFormat) newReader(r io.Reader, fieldsPerRecord int) recordReader {
+	if f == FormatTSV {
+		return &tsvReader{reader: bufio.NewReader(r), fieldsPerRecord: fieldsPerRecord}
+	}
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = fieldsPerRecord
+
+	return reader
+}
+
+// quoted reports whether fields of the format may be quoted, so line breaks inside quotes do not end records.
+func (f SourceFormat) quoted() bool {
+	return f != FormatTSV
+}
+
+// tsvReader reads tab-separated values (IANA text/tab-separated-values): fields are not quoted,
+// so quotes are a part of the values and records end with a line break. Empty lines are skipped.
+type tsvReader struct {
+	reader          *bufio.Reader
+	fieldsPerRecord int
+	line            int
+	offset          int64
+}
+
+func (r *tsvReader) Read() ([]string, error) {
+	for {
+		line, err := r.reader.ReadString('\n')
+		if err != nil && (!errors.Is(err, io.EOF) || line == "") {
+			return nil, err
+		}
+		r.offset += int64(len(line))
+		r.line++
+
+		line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+		if line == "" {
+			continue
+		}
+
+		record := strings.Split(line, "\t")
+		if r.fieldsPerRecord == 0 {
+			r.fieldsPerRecord = len(record)
+		} else if len(record) != r.fieldsPerRecord {
+			return record, &csv.ParseError{StartLine: r.line, Line: r.line, Column: 1, Err: csv.ErrFieldCount}
+		}
+
+		return record, nil
+	}
+}
+
+func (r *tsvReader) InputOffset() int64 {
+	return r.offset
+}
+
+// locationFields are the fields of the location in the column order of the default layout.
+var locationFields = []string{"ip_address", "country_code", "country", "city", "latitude", "longitude", "mystery_value"}
+
+// ColumnMapping maps the location fields to the header columns of the source files.
+// An empty mapping reads the fields by the column order of the default layout.
+type ColumnMapping map[string]string
+
+// ParseColumnMapping checks the fields of the mapping, the ip_address field must be mapped,
+// fields not mapped are empty.
+func ParseColumnMapping(columns map[string]string) (ColumnMapping, error) {
+	if len(columns) == 0 {
+		return nil, nil
+	}
+	for field, column := range columns {
+		if !isLocationField(field) {
+			return nil, fmt.Errorf("invalid column mapping: unknown field %q, must be one of %s", field, strings.Join(locationFields, ", "))
+		}
+		if strings.TrimSpace(column) == "" {
+			return nil, fmt.Errorf("invalid column mapping: empty column of field %q", field)
+		}
+	}
+	if _, ok := columns["ip_address"]; !ok {
+		return nil, fmt.Errorf("invalid column mapping: field ip_address is not mapped")
+	}
+
+	return columns, nil
+}
+
+func isLocationField(field string) bool {
+	for _, f := range locationFields {
+		if f == field {
+			return true
+		}
+	}
+
+	return false
+}
+
+// indexes returns the indexes of the columns of the location fields in the header, -1 for fields not mapped.
+// Header names are compared case-insensitively.
+func (m ColumnMapping) indexes(header []string) ([]int, error) {
+	indexes := make([]int, len(locationFields))
+	if len(m) == 0 {
+		if len(header) < len(locationFields) {
+			return nil, fmt.Errorf("header has %d columns, want %d", len(header), len(locationFields))
+		}
+		for i := range indexes {
+			indexes[i] = i
+		}
+
+		return indexes, nil
+	}
+
+	positions := make(map[string]int, len(header))
+	for i, name := range header {
+		if i == 0 {
+			// Files saved by spreadsheets may start with a byte order mark.
+			name = strings.TrimPrefix(name, "\ufeff")
+		}
+		positions[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	var missing []string
+	for i, field := range locationFields {
+		column, ok := m[field]
+		if !ok {
+			indexes[i] = -1
+			continue
+		}
+		position, ok := positions[strings.ToLower(strings.TrimSpace(column))]
+		if !ok {
+			missing = append(missing, column)
+			continue
+		}
+		indexes[i] = position
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return nil, fmt.Errorf("header has no columns %s", strings.Join(missing, ", "))
+	}
+
+	return indexes, nil
+}
+
+// DuplicatePolicy is the handling of records of an IP address repeated in the imported files.
+type DuplicatePolicy string
+
+const (
+	// DuplicatesLast keeps the last record of an IP address, a newer entry replaces the previous one.
+	DuplicatesLast DuplicatePolicy = "last"
+	// DuplicatesFirst keeps the first record of an IP address and discards the later ones.
+	DuplicatesFirst DuplicatePolicy = "first"
+)
+
+// ParseDuplicatePolicy parses the policy, an empty value is DuplicatesLast.
+func ParseDuplicatePolicy(value string) (DuplicatePolicy, error) {
+	switch policy := DuplicatePolicy(value); policy {
+	case "":
+		return DuplicatesLast, nil
+	case DuplicatesLast, DuplicatesFirst:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("invalid duplicate policy %q: must be last or first", value)
+	}
+}
@@ -0,0 +1,180 @@
+package processes
+
+import (
+	"context"
+	"encoding/csv"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vio/internal/models"
+)
+
+func TestColumnMapping_indexes(t *testing.T) {
+	tests := []struct {
+		name      string
+		columns   ColumnMapping
+		header    []string
+		want      []int
+		wantError string
+	}{
+		{
+			name:   "Default layout",
+			header: []string{"ip_address", "country_code", "country", "city", "latitude", "longitude", "mystery_value", "extra"},
+			want:   []int{0, 1, 2, 3, 4, 5, 6},
+		},
+		{
+			name:      "Default layout with missing columns",
+			header:    []string{"ip_address", "country_code"},
+			wantError: "header has 2 columns, want 7",
+		},
+		{
+			name:    "Mapped columns",
+			columns: ColumnMapping{"ip_address": "IP", "country_code": "iso", "latitude": "Lat", "longitude": "lon"},
+			header:  []string{"\ufeffip", "lon", " LAT ", "ISO"},
+			want:    []int{0, 3, -1, -1, 2, 1, -1},
+		},
+		{
+			name:      "Mapped columns are missing",
+			columns:   ColumnMapping{"ip_address": "ip", "city": "town", "country": "nation"},
+			header:    []string{"ip", "city"},
+			wantError: "header has no columns nation, town",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := tt.columns.indexes(tt.header)
+			if tt.wantError != "" {
+				assert.EqualError(t, err, tt.wantError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseColumnMapping(t *testing.T) {
+	got, err := ParseColumnMapping(nil)
+	assert.NoError(t, err)
+	assert.Nil(t, got)
+
+	got, err = ParseColumnMapping(map[string]string{"ip_address": "ip", "city": "town"})
+	assert.NoError(t, err)
+	assert.Equal(t, ColumnMapping{"ip_address": "ip", "city": "town"}, got)
+
+	_, err = ParseColumnMapping(map[string]string{"ip_address": "ip", "zip": "zip"})
+	assert.ErrorContains(t, err, `unknown field "zip"`)
+	_, err = ParseColumnMapping(map[string]string{"ip_address": " "})
+	assert.ErrorContains(t, err, "empty column")
+	_, err = ParseColumnMapping(map[string]string{"city": "town"})
+	assert.ErrorContains(t, err, "ip_address is not mapped")
+}
+
+func TestParseSourceFormat(t *testing.T) {
+	for _, value := range []string{"csv", "tsv"} {
+		got, err := ParseSourceFormat(value)
+		assert.NoError(t, err)
+		assert.Equal(t, SourceFormat(value), got)
+		assert.Equal(t, "."+value, got.Extension())
+	}
+	got, err := ParseSourceFormat("")
+	assert.NoError(t, err)
+	assert.Equal(t, FormatCSV, got)
+
+	_, err = ParseSourceFormat("json")
+	assert.Error(t, err)
+}
+
+func TestParseDuplicatePolicy(t *testing.T) {
+	for _, value := range []string{"last", "first"} {
+		got, err := ParseDuplicatePolicy(value)
+		assert.NoError(t, err)
+		assert.Equal(t, DuplicatePolicy(value), got)
+	}
+	got, err := ParseDuplicatePolicy("")
+	assert.NoError(t, err)
+	assert.Equal(t, DuplicatesLast, got)
+
+	_, err = ParseDuplicatePolicy("newest")
+	assert.Error(t, err)
+}
+
+func Test_loadData_vendorLayout(t *testing.T) {
+	path := t.TempDir()
+	content := "ip\tcity\tcc\tcountry\tlat\tlon\n" +
+		"1.1.1.1\tAmsterdam\tNL\tNetherlands\t52.37\t4.89\n" +
+		"2.2.2.2\tBrussels\tBE\tBelgium\t50.85\t4.35\n" +
+		"1.1.1.1\tUtrecht\tNL\tNetherlands\t52.09\t5.12\n"
+	require.NoError(t, os.WriteFile(filepath.Join(path, "feed.tsv"), []byte(content), 0o600))
+	// Files of other formats are not read.
+	require.NoError(t, os.WriteFile(filepath.Join(path, "other.csv"), []byte("not,a,feed\n"), 0o600))
+
+	opts := ImportOptions{
+		Format: FormatTSV,
+		Columns: ColumnMapping{
+			"ip_address":   "ip",
+			"country_code": "cc",
+			"country":      "country",
+			"city":         "city",
+			"latitude":     "lat",
+			"longitude":    "lon",
+		},
+		Duplicates: DuplicatesFirst,
+	}
+	got, gotStatistics, err := loadData(context.Background(), path, 1, opts, nil)
+	require.NoError(t, err)
+
+	want := []models.Location{
+		{IPAddress: "1.1.1.1", CountryCode: "NL", Country: "Netherlands", City: "Amsterdam", Latitude: 52.37, Longitude: 4.89, QualityScore: 100},
+		{IPAddress: "2.2.2.2", CountryCode: "BE", Country: "Belgium", City: "Brussels", Latitude: 50.85, Longitude: 4.35, QualityScore: 100},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatal("Result mismatch\n", diff)
+	}
+	assert.Equal(t, int64(1), gotStatistics.FilesCount)
+	assert.Equal(t, int64(3), gotStatistics.Accepted)
+	assert.Equal(t, int64(1), gotStatistics.Duplicates)
+}
+
+func Test_tsvReader(t *testing.T) {
+	content := "ip_address\tcity\r\n" +
+		"1.1.1.1\t6\" Street\r\n" +
+		"\n" +
+		"2.2.2.2\t\"Den Haag\n" +
+		"3.3.3.3\n"
+	reader := FormatTSV.newReader(strings.NewReader(content), 0)
+
+	var got [][]string
+	for {
+		record, err := reader.Read()
+		if err != nil {
+			var parseErr *csv.ParseError
+			require.ErrorAs(t, err, &parseErr)
+			assert.Equal(t, 5, parseErr.Line)
+			assert.ErrorIs(t, err, csv.ErrFieldCount)
+			break
+		}
+		got = append(got, record)
+	}
+
+	want := [][]string{
+		{"ip_address", "city"},
+		{"1.1.1.1", "6\" Street"},
+		{"2.2.2.2", "\"Den Haag"},
+	}
+	assert.Equal(t, want, got)
+	assert.Equal(t, int64(len(content)), reader.InputOffset())
+
+	_, err := reader.Read()
+	assert.ErrorIs(t, err, io.EOF)
+}
@@ -0,0 +1,98 @@
+package processes
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+
+	"vio/internal/models"
+)
+
+// SQLStatistics aggregates locations grouped by the given columns.
+// Coordinates are averaged as unit vectors, so the centroid is correct near the antimeridian.
+var SQLStatistics = `SELECT
+	COALESCE(country_code, ''),
+	COALESCE(country, ''),
+	%[1]s,
+	COUNT(*),
+	COALESCE(AVG(COS(RADIANS(latitude)) * COS(RADIANS(longitude))), 0),
+	COALESCE(AVG(COS(RADIANS(latitude)) * SIN(RADIANS(longitude))), 0),
+	COALESCE(AVG(SIN(RADIANS(latitude))), 0),
+	COALESCE(MIN(mystery_value), 0),
+	COALESCE(MAX(mystery_value), 0),
+	COALESCE(AVG(mystery_value), 0),
+	COALESCE(SUM(mystery_value), 0)
+FROM location
+GROUP BY %[2]s
+ORDER BY COUNT(*) DESC, %[2]s`
+
+// GetCountryStatistics returns statistics per country ordered by the number of IP addresses.
+// When top is positive only the top countries are returned.
+func GetCountryStatistics(ctx context.Context, db *sql.DB, top int) ([]models.LocationStatistics, error) {
+	query := fmt.Sprintf(SQLStatistics, "''", "1, 2")
+
+	return getStatistics(ctx, db, query, top)
+}
+
+// GetCityStatistics returns statistics per city ordered by the number of IP addresses.
+// When top is positive only the top cities are returned.
+func GetCityStatistics(ctx context.Context, db *sql.DB, top int) ([]models.LocationStatistics, error) {
+	query := fmt.Sprintf(SQLStatistics, "COALESCE(city, '')", "1, 2, 3")
+
+	return getStatistics(ctx, db, query, top)
+}
+
+func getStatistics(ctx context.Context, db *sql.DB, query string, top int) ([]models.LocationStatistics, error) {
+	var args []any
+	if top > 0 {
+		query += " LIMIT $1"
+		args = append(args, top)
+	}
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	statistics := make([]models.LocationStatistics, 0)
+	for rows.Next() {
+		stat := models.LocationStatistics{MysteryValue: &models.MysteryValueStatistics{}}
+		var x, y, z float64
+		err := rows.Scan(
+			&stat.CountryCode,
+			&stat.Country,
+			&stat.City,
+			&stat.Count,
+			&x,
+			&y,
+			&z,
+			&stat.MysteryValue.Min,
+			&stat.MysteryValue.Max,
+			&stat.MysteryValue.Avg,
+			&stat.MysteryValue.Sum,
+		)
+		if err != nil {
+			return nil, err
+		}
+		stat.Latitude, stat.Longitude = centroid(x, y, z)
+		statistics = append(statistics, stat)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return statistics, nil
+}
+
+// centroid converts the average of unit vectors back to latitude and longitude in degrees.
+func centroid(x, y, z float64) (latitude, longitude float64) {
+	if x == 0 && y == 0 && z == 0 {
+		return 0, 0
+	}
+	latitude = math.Atan2(z, math.Hypot(x, y)) * 180 / math.Pi
+	longitude = math.Atan2(y, x) * 180 / math.Pi
+
+	return latitude, longitude
+}
@@ -0,0 +1,79 @@
+package processes
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vio/internal/models"
+)
+
+func TestGetCityStatistics(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	columns := []string{"country_code", "country", "city", "count", "x", "y", "z", "min", "max", "avg", "sum"}
+	rows := sqlmock.NewRows(columns).
+		AddRow("TL", "Saudi Arabia", "Gradymouth", 2, 0.0, 1.0, 0.0, 10, 30, 20.0, 40)
+
+	mock.ExpectQuery(`SELECT (.+) FROM location GROUP BY 1, 2, 3 ORDER BY COUNT\(\*\) DESC, 1, 2, 3 LIMIT \$1`).
+		WithArgs(5).
+		WillReturnRows(rows)
+
+	gotResult, err := GetCityStatistics(context.Background(), db, 5)
+	require.NoError(t, err)
+
+	wantResult := []models.LocationStatistics{
+		{
+			CountryCode: "TL",
+			Country:     "Saudi Arabia",
+			City:        "Gradymouth",
+			Count:       2,
+			Latitude:    0,
+			Longitude:   90,
+			MysteryValue: &models.MysteryValueStatistics{
+				Min: 10,
+				Max: 30,
+				Avg: 20,
+				Sum: 40,
+			},
+		},
+	}
+	diff := cmp.Diff(wantResult, gotResult, cmpopts.EquateApprox(0, 1e-9))
+	if diff != "" {
+		t.Fatal("result mismatch\n", diff)
+	}
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func Test_centroid(t *testing.T) {
+	toVector := func(latitude, longitude float64) (float64, float64, float64) {
+		lat := latitude * math.Pi / 180
+		lon := longitude * math.Pi / 180
+		return math.Cos(lat) * math.Cos(lon), math.Cos(lat) * math.Sin(lon), math.Sin(lat)
+	}
+
+	// Two points on both sides of the antimeridian.
+	x1, y1, z1 := toVector(10, 179)
+	x2, y2, z2 := toVector(10, -179)
+
+	latitude, longitude := centroid((x1+x2)/2, (y1+y2)/2, (z1+z2)/2)
+	assert.InDelta(t, 10, latitude, 0.01)
+	assert.InDelta(t, 180, math.Abs(longitude), 1e-9)
+
+	latitude, longitude = centroid(0, 0, 0)
+	assert.Zero(t, latitude)
+	assert.Zero(t, longitude)
+}
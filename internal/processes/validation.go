@@ -0,0 +1,99 @@
+package processes
+
+import (
+	"fmt"
+	"net/netip"
+
+	"vio/internal/models"
+)
+
+// BogonPolicy is the handling of special-purpose (bogon) addresses:
+// private, loopback, link-local, multicast, documentation and reserved ones.
+type BogonPolicy string
+
+const (
+	// BogonsAccept handles special-purpose addresses as any other address.
+	BogonsAccept BogonPolicy = "accept"
+	// BogonsReject discards special-purpose addresses at the import,
+	// the API answers them as reserved addresses without a lookup.
+	BogonsReject BogonPolicy = "reject"
+	// BogonsTag imports special-purpose addresses counting them in the statistics,
+	// the API returns their address class and answers them as reserved addresses when they are not found.
+	BogonsTag BogonPolicy = "tag"
+)
+
+// ParseBogonPolicy parses the policy, an empty value is BogonsAccept.
+func ParseBogonPolicy(value string) (BogonPolicy, error) {
+	switch policy := BogonPolicy(value); policy {
+	case "":
+		return BogonsAccept, nil
+	case BogonsAccept, BogonsReject, BogonsTag:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("invalid bogon policy %q: must be accept, reject or tag", value)
+	}
+}
+
+// Reserved returns the class of the address and whether the policy handles it as a reserved address.
+func (p BogonPolicy) Reserved(addr netip.Addr) (AddressClass, bool) {
+	class := ClassifyIPAddress(addr)
+
+	return class, class != ClassPublic && (p == BogonsReject || p == BogonsTag)
+}
+
+// ValidationPolicy of the imported records.
+type ValidationPolicy struct {
+	// Bogons is the handling of special-purpose addresses.
+	Bogons BogonPolicy
+	// Countries is the handling of records whose country code and country name disagree.
+	Countries CountryPolicy
+	// Coordinates is the handling of records whose coordinates are outside of the country.
+	Coordinates CoordinatePolicy
+	// MinQuality is the minimum quality score of imported locations, 0 imports all locations.
+	MinQuality int
+}
+
+// validate checks the location, converts its IP address to the canonical form and normalizes
+// its country and city and scores its quality. Special-purpose addresses, normalized records,
+// country and coordinate mismatches and locations below the minimum quality are counted in the statistics.
+// It returns false when the location must be discarded.
+func (p ValidationPolicy) validate(location *models.Location, statistics *models.LoadStatistics) bool {
+	addr, ok := ParseIPAddress(location.IPAddress)
+	if !ok {
+		return false
+	}
+	class, reserved := p.Bogons.Reserved(addr)
+	if reserved {
+		statistics.Reserved++
+		if p.Bogons == BogonsReject {
+			return false
+		}
+	}
+	location.IPAddress = addr.String()
+	if normalizeLocation(location) {
+		statistics.Normalized++
+	}
+
+	if !p.Countries.checkCountry(location, statistics) || !p.Coordinates.checkCoordinates(location, statistics) {
+		return false
+	}
+
+	scoreLocation(location, class)
+	if location.QualityScore < p.MinQuality {
+		statistics.LowQuality++
+		return false
+	}
+
+	return true
+}
+
+// addValidationStatistics adds the discarded records and the counters of the validation of a chunk.
+func addValidationStatistics(statistics *models.LoadStatistics, chunk models.LoadStatistics) {
+	statistics.Discarded += chunk.Discarded
+	statistics.Reserved += chunk.Reserved
+	statistics.Normalized += chunk.Normalized
+	statistics.LowQuality += chunk.LowQuality
+	statistics.CountryMismatches += chunk.CountryMismatches
+	statistics.CountryCorrections += chunk.CountryCorrections
+	statistics.CoordinateMismatches += chunk.CoordinateMismatches
+}
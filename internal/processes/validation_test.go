@@ -0,0 +1,134 @@
+package processes
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vio/internal/models"
+)
+
+func TestValidationPolicy_validate(t *testing.T) {
+	tests := []struct {
+		name           string
+		policy         ValidationPolicy
+		ipAddress      string
+		want           bool
+		wantIPAddress  string
+		wantReserved   int64
+		wantLowQuality int64
+	}{
+		{
+			name:          "Public address",
+			policy:        ValidationPolicy{Bogons: BogonsReject},
+			ipAddress:     "70.95.73.73",
+			want:          true,
+			wantIPAddress: "70.95.73.73",
+		},
+		{
+			name:          "Canonical form of IPv6 address",
+			ipAddress:     "2A00:1450:4001:081B:0000:0000:0000:200E",
+			want:          true,
+			wantIPAddress: "2a00:1450:4001:81b::200e",
+		},
+		{
+			name:      "Invalid address",
+			ipAddress: "not your IP address",
+			want:      false,
+		},
+		{
+			name:          "Private address is accepted by default",
+			ipAddress:     "192.168.1.1",
+			want:          true,
+			wantIPAddress: "192.168.1.1",
+		},
+		{
+			name:         "Private address is rejected",
+			policy:       ValidationPolicy{Bogons: BogonsReject},
+			ipAddress:    "192.168.1.1",
+			want:         false,
+			wantReserved: 1,
+		},
+		{
+			name:         "Documentation address is rejected",
+			policy:       ValidationPolicy{Bogons: BogonsReject},
+			ipAddress:    "2001:db8::1",
+			want:         false,
+			wantReserved: 1,
+		},
+		{
+			name:          "Loopback address is tagged",
+			policy:        ValidationPolicy{Bogons: BogonsTag},
+			ipAddress:     "::ffff:127.0.0.1",
+			want:          true,
+			wantIPAddress: "127.0.0.1",
+			wantReserved:  1,
+		},
+		{
+			name:           "Low quality is rejected",
+			policy:         ValidationPolicy{MinQuality: 50},
+			ipAddress:      "70.95.73.73",
+			want:           false,
+			wantLowQuality: 1,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var statistics models.LoadStatistics
+			location := models.Location{IPAddress: tt.ipAddress}
+			assert.Equal(t, tt.want, tt.policy.validate(&location, &statistics))
+			if tt.want {
+				assert.Equal(t, tt.wantIPAddress, location.IPAddress)
+			}
+			assert.Equal(t, tt.wantReserved, statistics.Reserved)
+			assert.Equal(t, tt.wantLowQuality, statistics.LowQuality)
+		})
+	}
+}
+
+func TestParseBogonPolicy(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    BogonPolicy
+		wantErr bool
+	}{
+		{value: "", want: BogonsAccept},
+		{value: "accept", want: BogonsAccept},
+		{value: "reject", want: BogonsReject},
+		{value: "tag", want: BogonsTag},
+		{value: "drop", wantErr: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.value, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := ParseBogonPolicy(tt.value)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestBogonPolicy_Reserved(t *testing.T) {
+	addr := netip.MustParseAddr("100.64.0.1")
+
+	class, reserved := BogonsAccept.Reserved(addr)
+	assert.Equal(t, ClassPrivate, class)
+	assert.False(t, reserved)
+
+	_, reserved = BogonsTag.Reserved(addr)
+	assert.True(t, reserved)
+
+	_, reserved = BogonsReject.Reserved(netip.MustParseAddr("8.8.8.8"))
+	assert.False(t, reserved)
+}
@@ -0,0 +1,221 @@
+package report
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+
+	"vio/internal/models"
+	"vio/internal/processes"
+)
+
+// terminalInterval is the refresh interval of the progress bar.
+const terminalInterval = 200 * time.Millisecond
+
+// smoothing is the weight of the latest sample in the rates.
+const smoothing = 0.3
+
+const barWidth = 30
+
+// Rates of an import smoothed over the samples.
+type Rates struct {
+	RowsReadPerSec    float64
+	RowsWrittenPerSec float64
+	BytesReadPerSec   float64
+	// ETA is the estimated time left in the current phase, negative when unknown.
+	ETA time.Duration
+}
+
+// Reporter periodically reports the progress of an import.
+type Reporter struct {
+	source   func() models.ImportProgress
+	interval time.Duration
+	render   func(models.ImportProgress, Rates)
+	finish   func()
+	now      func() time.Time
+
+	last     models.ImportProgress
+	lastTime time.Time
+	rates    Rates
+	started  bool
+}
+
+// New creates a reporter drawing a progress bar when w is a terminal,
+// otherwise logging the progress every interval.
+func New(w *os.File, log *slog.Logger, source func() models.ImportProgress, interval time.Duration) *Reporter {
+	if term.IsTerminal(int(w.Fd())) {
+		return NewTerminal(w, source)
+	}
+
+	return NewLog(log, source, interval)
+}
+
+// NewTerminal creates a reporter redrawing a progress bar in one line.
+func NewTerminal(w io.Writer, source func() models.ImportProgress) *Reporter {
+	return &Reporter{
+		source:   source,
+		interval: terminalInterval,
+		render: func(p models.ImportProgress, rates Rates) {
+			fmt.Fprintf(w, "\r%s\x1b[K", FormatBar(p, rates))
+		},
+		finish: func() {
+			fmt.Fprintln(w)
+		},
+		now: time.Now,
+	}
+}
+
+// NewLog creates a reporter writing structured log lines.
+func NewLog(log *slog.Logger, source func() models.ImportProgress, interval time.Duration) *Reporter {
+	return &Reporter{
+		source:   source,
+		interval: interval,
+		render: func(p models.ImportProgress, rates Rates) {
+			attrs := []any{
+				slog.String("phase", p.Phase),
+				slog.String("file", p.File),
+				slog.Int64("file_bytes_read", p.FileBytesRead),
+				slog.Int64("file_size", p.FileSize),
+				slog.Int64("rows_read", p.RowsRead),
+				slog.Int64("rows_written", p.RowsWritten),
+				slog.Int64("rows_to_write", p.RowsToWrite),
+				slog.Float64("rows_read_per_sec", round(rates.RowsReadPerSec)),
+				slog.Float64("rows_written_per_sec", round(rates.RowsWrittenPerSec)),
+			}
+			if rates.ETA >= 0 {
+				attrs = append(attrs, slog.String("eta", rates.ETA.String()))
+			}
+			log.Info("import progress", attrs...)
+		},
+		finish: func() {},
+		now:    time.Now,
+	}
+}
+
+// Run reports the progress until the context is done, then reports the final state.
+func (r *Reporter) Run(ctx context.Context) {
+	if r.interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	r.sample()
+	for {
+		select {
+		case <-ctx.Done():
+			r.render(r.sample())
+			r.finish()
+			return
+		case <-ticker.C:
+			r.render(r.sample())
+		}
+	}
+}
+
+// sample takes the current progress and updates the rates.
+func (r *Reporter) sample() (models.ImportProgress, Rates) {
+	p := r.source()
+	now := r.now()
+
+	if r.started {
+		if elapsed := now.Sub(r.lastTime).Seconds(); elapsed > 0 {
+			r.rates.RowsReadPerSec = smooth(r.rates.RowsReadPerSec, float64(p.RowsRead-r.last.RowsRead)/elapsed)
+			r.rates.RowsWrittenPerSec = smooth(r.rates.RowsWrittenPerSec, float64(p.RowsWritten-r.last.RowsWritten)/elapsed)
+			r.rates.BytesReadPerSec = smooth(r.rates.BytesReadPerSec, float64(p.BytesRead-r.last.BytesRead)/elapsed)
+		}
+	}
+	r.started = true
+	r.last = p
+	r.lastTime = now
+	r.rates.ETA = eta(p, r.rates)
+
+	return p, r.rates
+}
+
+// FormatBar formats the progress as a line with a bar of the current phase.
+func FormatBar(p models.ImportProgress, rates Rates) string {
+	fraction := phaseFraction(p)
+	filled := int(fraction * barWidth)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-7s [%s%s] %3.0f%%", p.Phase, strings.Repeat("=", filled), strings.Repeat(" ", barWidth-filled), fraction*100)
+	if p.Phase == processes.PhaseReading && p.File != "" {
+		fmt.Fprintf(&b, " %s %s/%s", p.File, formatBytes(p.FileBytesRead), formatBytes(p.FileSize))
+	}
+	fmt.Fprintf(&b, " | read %.0f rows/s | written %.0f rows/s", rates.RowsReadPerSec, rates.RowsWrittenPerSec)
+	if rates.ETA >= 0 {
+		fmt.Fprintf(&b, " | ETA %s", rates.ETA.Round(time.Second))
+	}
+
+	return b.String()
+}
+
+// phaseFraction returns the completed part of the current phase from 0 to 1.
+func phaseFraction(p models.ImportProgress) float64 {
+	var fraction float64
+	switch p.Phase {
+	case processes.PhaseReading:
+		if p.BytesTotal > 0 {
+			fraction = float64(p.BytesRead) / float64(p.BytesTotal)
+		}
+	case processes.PhaseWriting:
+		if p.RowsToWrite > 0 {
+			fraction = float64(p.RowsWritten) / float64(p.RowsToWrite)
+		}
+	case processes.PhaseDone:
+		fraction = 1
+	}
+
+	return min(max(fraction, 0), 1)
+}
+
+// eta estimates the time left in the current phase.
+func eta(p models.ImportProgress, rates Rates) time.Duration {
+	var seconds float64
+	switch {
+	case p.Phase == processes.PhaseReading && rates.BytesReadPerSec > 0 && p.BytesTotal > 0:
+		seconds = float64(p.BytesTotal-p.BytesRead) / rates.BytesReadPerSec
+	case p.Phase == processes.PhaseWriting && rates.RowsWrittenPerSec > 0:
+		seconds = float64(p.RowsToWrite-p.RowsWritten) / rates.RowsWrittenPerSec
+	case p.Phase == processes.PhaseDone:
+		return 0
+	default:
+		return -1
+	}
+
+	return time.Duration(max(seconds, 0) * float64(time.Second))
+}
+
+func smooth(previous, current float64) float64 {
+	if previous == 0 {
+		return current
+	}
+
+	return smoothing*current + (1-smoothing)*previous
+}
+
+func round(v float64) float64 {
+	return float64(int64(v*10+0.5)) / 10
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
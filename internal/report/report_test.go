@@ -0,0 +1,97 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"vio/internal/models"
+	"vio/internal/processes"
+)
+
+func TestFormatBar(t *testing.T) {
+	tests := []struct {
+		name     string
+		progress models.ImportProgress
+		rates    Rates
+		want     string
+	}{
+		{
+			name: "Reading",
+			progress: models.ImportProgress{
+				Phase:         processes.PhaseReading,
+				BytesTotal:    4096,
+				BytesRead:     1024,
+				File:          "data_dump.csv",
+				FileSize:      2048,
+				FileBytesRead: 1024,
+				RowsRead:      100,
+			},
+			rates: Rates{RowsReadPerSec: 50, ETA: 3 * time.Second},
+			want:  "reading [=======                       ]  25% data_dump.csv 1.0 KiB/2.0 KiB | read 50 rows/s | written 0 rows/s | ETA 3s",
+		},
+		{
+			name: "Writing without estimate",
+			progress: models.ImportProgress{
+				Phase:       processes.PhaseWriting,
+				RowsWritten: 50,
+				RowsToWrite: 100,
+			},
+			rates: Rates{ETA: -1},
+			want:  "writing [===============               ]  50% | read 0 rows/s | written 0 rows/s",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.want, FormatBar(tt.progress, tt.rates))
+		})
+	}
+}
+
+func TestReporter_sample(t *testing.T) {
+	progress := models.ImportProgress{Phase: processes.PhaseWriting, RowsToWrite: 1000}
+	now := time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC)
+
+	r := NewLog(slog.Default(), func() models.ImportProgress { return progress }, time.Second)
+	r.now = func() time.Time { return now }
+
+	_, rates := r.sample()
+	assert.Equal(t, time.Duration(-1), rates.ETA)
+
+	progress.RowsWritten = 100
+	now = now.Add(time.Second)
+	_, rates = r.sample()
+	assert.Equal(t, 100.0, rates.RowsWrittenPerSec)
+	assert.Equal(t, 9*time.Second, rates.ETA)
+
+	// The rate is smoothed over the samples.
+	progress.RowsWritten = 300
+	now = now.Add(time.Second)
+	_, rates = r.sample()
+	assert.InDelta(t, 130.0, rates.RowsWrittenPerSec, 1e-9)
+}
+
+func TestNewLog(t *testing.T) {
+	var buf bytes.Buffer
+	log := slog.New(slog.NewTextHandler(&buf, nil))
+	source := func() models.ImportProgress {
+		return models.ImportProgress{Phase: processes.PhaseDone, RowsRead: 10, RowsWritten: 10, RowsToWrite: 10}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	NewLog(log, source, time.Hour).Run(ctx)
+
+	line := buf.String()
+	assert.True(t, strings.Contains(line, `msg="import progress" phase=done`), line)
+	assert.True(t, strings.Contains(line, "rows_written=10"), line)
+	assert.True(t, strings.Contains(line, "eta=0s"), line)
+}
@@ -0,0 +1,220 @@
+package shape
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/language/display"
+
+	"vio/internal/models"
+)
+
+// Shaper filters fields of location responses and localizes country names.
+type Shaper struct {
+	// allowed fields, nil means all fields are allowed.
+	allowed map[string]bool
+}
+
+// New creates a shaper exposing only the allowed fields of locations,
+// an empty list allows all fields.
+func New(allowedFields []string) (*Shaper, error) {
+	s := &Shaper{}
+	if len(allowedFields) == 0 {
+		return s, nil
+	}
+
+	known := jsonFieldNames(reflect.TypeOf(models.NearbyLocation{}))
+	s.allowed = make(map[string]bool, len(allowedFields))
+	for _, field := range allowedFields {
+		field = strings.TrimSpace(field)
+		if !known[field] {
+			return nil, fmt.Errorf("unknown response field %q", field)
+		}
+		s.allowed[field] = true
+	}
+
+	return s, nil
+}
+
+// Shape is the response shaping requested by the query.
+type Shape struct {
+	fields map[string]bool
+	namer  display.Namer
+}
+
+// Parse reads the fields and lang parameters of the query for responses of the given type.
+func (s *Shaper) Parse(query url.Values, responseType any) (*Shape, error) {
+	known := jsonFieldNames(reflect.TypeOf(responseType))
+	sh := &Shape{}
+
+	if value := query.Get("fields"); value != "" {
+		sh.fields = make(map[string]bool)
+		for _, field := range strings.Split(value, ",") {
+			field = strings.TrimSpace(field)
+			if field == "" {
+				continue
+			}
+			if !known[field] || !s.Allowed(field) {
+				return nil, fmt.Errorf("invalid fields: unknown field %q", field)
+			}
+			sh.fields[field] = true
+		}
+	} else if s.allowed != nil {
+		sh.fields = s.allowed
+	}
+
+	if value := query.Get("lang"); value != "" {
+		tag, err := language.Parse(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid lang: %q", value)
+		}
+		// Regions returns nil for languages without display names.
+		sh.namer = display.Regions(tag)
+		if sh.namer == nil {
+			return nil, fmt.Errorf("unsupported lang: %q", value)
+		}
+	}
+
+	return sh, nil
+}
+
+// Allowed reports whether the location field is exposed, a nil shaper allows all fields.
+func (s *Shaper) Allowed(field string) bool {
+	return s == nil || s.allowed == nil || s.allowed[field]
+}
+
+// HideStatistics clears the fields of the statistics hidden by the allow-list of location fields,
+// so the aggregates do not expose them.
+func (s *Shaper) HideStatistics(stat *models.LocationStatistics) {
+	if !s.Allowed("country_code") {
+		stat.CountryCode = ""
+	}
+	if !s.Allowed("country") {
+		stat.Country = ""
+	}
+	if !s.Allowed("city") {
+		stat.City = ""
+	}
+	if !s.Allowed("latitude") || !s.Allowed("longitude") {
+		stat.Latitude, stat.Longitude = 0, 0
+	}
+	if !s.Allowed("mystery_value") {
+		stat.MysteryValue = nil
+	}
+}
+
+// isEmpty reports whether the response is returned as is.
+func (sh *Shape) isEmpty() bool {
+	return sh.fields == nil && sh.namer == nil
+}
+
+// Apply converts the value to a JSON object with the requested fields in the original order.
+func (sh *Shape) Apply(v any) (json.RawMessage, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if sh.isEmpty() {
+		return data, nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	values := make(map[string]json.RawMessage)
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, _ := token.(string)
+
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+		values[key] = value
+	}
+
+	if sh.namer != nil {
+		sh.localizeCountry(values)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for _, key := range keys {
+		if sh.fields != nil && !sh.fields[key] {
+			continue
+		}
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		name, _ := json.Marshal(key)
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(values[key])
+	}
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
+
+// localizeCountry replaces the country name by the name of the country code in the requested language.
+func (sh *Shape) localizeCountry(values map[string]json.RawMessage) {
+	var code string
+	if err := json.Unmarshal(values["country_code"], &code); err != nil || code == "" {
+		return
+	}
+	region, err := language.ParseRegion(code)
+	if err != nil {
+		return
+	}
+	name := sh.namer.Name(region)
+	if name == "" {
+		return
+	}
+	values["country"], _ = json.Marshal(name)
+}
+
+// ApplyAll shapes every item of the slice.
+func ApplyAll[T any](sh *Shape, items []T) ([]json.RawMessage, error) {
+	result := make([]json.RawMessage, 0, len(items))
+	for _, item := range items {
+		data, err := sh.Apply(item)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, data)
+	}
+
+	return result, nil
+}
+
+// jsonFieldNames returns JSON names of the struct fields including embedded structs.
+func jsonFieldNames(t reflect.Type) map[string]bool {
+	names := make(map[string]bool)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			for name := range jsonFieldNames(field.Type) {
+				names[name] = true
+			}
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			names[name] = true
+		}
+	}
+
+	return names
+}
@@ -0,0 +1,127 @@
+package shape
+
+import (
+	"encoding/json"
+	"net/url"
+	"testing"
+
+	"vio/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShaper(t *testing.T) {
+	location := models.Location{
+		IPAddress:    "70.95.73.73",
+		CountryCode:  "TL",
+		Country:      "Saudi Arabia",
+		City:         "Gradymouth",
+		Latitude:     -49.16675918861615,
+		Longitude:    -86.05920084416894,
+		MysteryValue: 2559997162,
+	}
+
+	tests := []struct {
+		name            string
+		allowedFields   []string
+		query           string
+		want            string
+		wantErrorString string
+	}{
+		{
+			name:  "Full response",
+			query: "",
+			want:  `{"ip_address":"70.95.73.73","country_code":"TL","country":"Saudi Arabia","city":"Gradymouth","latitude":-49.16675918861615,"longitude":-86.05920084416894,"mystery_value":2559997162,"quality_score":0}`,
+		},
+		{
+			name:  "Requested fields keep the original order",
+			query: "fields=city,ip_address",
+			want:  `{"ip_address":"70.95.73.73","city":"Gradymouth"}`,
+		},
+		{
+			name:  "Country name in the requested language",
+			query: "fields=country_code,country&lang=de",
+			want:  `{"country_code":"TL","country":"Timor-Leste"}`,
+		},
+		{
+			name:          "Allow-list hides fields by default",
+			allowedFields: []string{"ip_address", "country"},
+			query:         "",
+			want:          `{"ip_address":"70.95.73.73","country":"Saudi Arabia"}`,
+		},
+		{
+			name:            "Field outside of the allow-list",
+			allowedFields:   []string{"ip_address", "country"},
+			query:           "fields=mystery_value",
+			wantErrorString: `invalid fields: unknown field "mystery_value"`,
+		},
+		{
+			name:            "Unknown field",
+			query:           "fields=distance_km",
+			wantErrorString: `invalid fields: unknown field "distance_km"`,
+		},
+		{
+			name:            "Invalid lang",
+			query:           "lang=-",
+			wantErrorString: `invalid lang: "-"`,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			shaper, err := New(tt.allowedFields)
+			require.NoError(t, err)
+			query, err := url.ParseQuery(tt.query)
+			require.NoError(t, err)
+
+			sh, err := shaper.Parse(query, models.Location{})
+			if tt.wantErrorString != "" {
+				assert.EqualError(t, err, tt.wantErrorString)
+				return
+			}
+			require.NoError(t, err)
+
+			got, err := sh.Apply(location)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(got))
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	_, err := New([]string{"ip_address", "distance_km"})
+	assert.NoError(t, err)
+
+	_, err = New([]string{"secret"})
+	assert.EqualError(t, err, `unknown response field "secret"`)
+}
+
+func TestShaper_HideStatistics(t *testing.T) {
+	stat := models.LocationStatistics{
+		CountryCode:  "NL",
+		Country:      "Netherlands",
+		City:         "Amsterdam",
+		Count:        2,
+		Latitude:     52.37,
+		Longitude:    4.89,
+		MysteryValue: &models.MysteryValueStatistics{Min: 1, Max: 3, Avg: 2, Sum: 4},
+	}
+
+	var all *Shaper
+	got := stat
+	all.HideStatistics(&got)
+	assert.Equal(t, stat, got)
+
+	shaper, err := New([]string{"ip_address", "country_code", "country", "city"})
+	assert.NoError(t, err)
+	got = stat
+	shaper.HideStatistics(&got)
+	assert.Equal(t, models.LocationStatistics{CountryCode: "NL", Country: "Netherlands", City: "Amsterdam", Count: 2}, got)
+
+	data, err := json.Marshal(got)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(data), "mystery_value")
+}